@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// archiveTagPrefix is prepended to a branch name to form the tag that keeps
+// an archived branch's commits.
+const archiveTagPrefix = "archive/"
+
+// archiveBranch replaces branch with an annotated archive tag at its tip, so
+// its commits stay reachable without it showing up as a branch.
+func archiveBranch(branch string) error {
+	tag := archiveTagPrefix + branch
+	if output, err := gitCombinedOutput("tag", "-a", "-m", "Archived branch "+branch, tag, "refs/heads/"+branch); err != nil {
+		return fmt.Errorf("cannot create tag %s: %s", tag, output)
+	}
+	run, err := trashBranches([]string{branch})
+	if err != nil {
+		warn("Cannot keep %s for undo: %s", branch, err)
+	}
+	if output, err := gitCombinedOutput("branch", "-D", branch); err != nil {
+		if run != "" {
+			untrashBranches(run, []string{branch})
+		}
+		return fmt.Errorf("created tag %s but cannot delete the branch: %s", tag, output)
+	}
+	return nil
+}
+
+// archiveBranches confirms and archives branches, returning how many were
+// archived.
+func archiveBranches(branches []string, currentBranch string) int {
+	branches = filterCurrentBranch(branches, currentBranch)
+	branches = filterProtectedBranches(branches)
+	branches = filterBusyBranches(branches)
+	if len(branches) == 0 {
+		return 0
+	}
+
+	title("The following branches will be archived as %s<name> tags and deleted:", archiveTagPrefix)
+	for _, branch := range branches {
+		info(branch)
+	}
+	if !confirmDeletion() {
+		return 0
+	}
+
+	archived := 0
+	for _, branch := range branches {
+		if err := archiveBranch(branch); err != nil {
+			warn("Error archiving %s: %s", branch, err)
+			continue
+		}
+		info("Archived %s as %s%s", branch, archiveTagPrefix, branch)
+		archived++
+	}
+
+	branchStr := "branches"
+	if len(branches) == 1 {
+		branchStr = "branch"
+	}
+	status("%d out of %d %s archived.", archived, len(branches), branchStr)
+	return archived
+}
+
+// archivedBranch is a branch kept as an archive tag.
+type archivedBranch struct {
+	name     string
+	archived time.Time
+	sha      string
+	subject  string
+}
+
+// runArchive lists archived branches or restores one.
+func runArchive(args []string) {
+	switch {
+	case len(args) >= 1 && len(args) <= 2 && args[0] == "list":
+		pattern := ""
+		if len(args) == 2 {
+			pattern = args[1]
+		}
+		listArchivedBranches(pattern)
+	case len(args) == 2 && args[0] == "restore":
+		restoreArchivedBranch(strings.TrimPrefix(args[1], archiveTagPrefix))
+	default:
+		usageError("archive")
+	}
+}
+
+// archivedBranches reads the archive tags, oldest archived first. The
+// tagger date of a tag is when its branch was archived.
+func archivedBranches() ([]archivedBranch, error) {
+	lines, err := gitLines("for-each-ref", "--sort=taggerdate",
+		"--format=%(refname)%00%(taggerdate:unix)%00%(*objectname)%00%(*subject)", "refs/tags/"+archiveTagPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var archived []archivedBranch
+	for _, line := range lines {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected for-each-ref output %q", line)
+		}
+		a := archivedBranch{
+			name:    strings.TrimPrefix(fields[0], "refs/tags/"+archiveTagPrefix),
+			sha:     fields[2],
+			subject: fields[3],
+		}
+		if seconds, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			a.archived = time.Unix(seconds, 0)
+		}
+		archived = append(archived, a)
+	}
+	return archived, nil
+}
+
+func listArchivedBranches(pattern string) {
+	all, err := archivedBranches()
+	if err != nil {
+		warn("Error reading archive tags: %s", err)
+		exit(1)
+	}
+	var archived []archivedBranch
+	for _, a := range all {
+		// Tags made by hand may be lightweight and carry no tip or date.
+		if a.sha != "" && (pattern == "" || matchesPattern(a.name, pattern)) {
+			archived = append(archived, a)
+		}
+	}
+	if len(archived) == 0 {
+		status("No archived branches.")
+		return
+	}
+
+	title("Archived branches")
+	t := table{}
+	for i, a := range archived {
+		if plainOutput {
+			info("branch %d of %d: %s, archived %s, tip %s %s", i+1, len(archived), a.name, formatWhen(a.archived), shortSHA(a.sha), a.subject)
+		} else {
+			t.addRow(a.name, "archived "+formatWhen(a.archived), shortSHA(a.sha), a.subject)
+		}
+	}
+	for _, line := range t.lines() {
+		info("%s", line)
+	}
+}
+
+// restoreArchivedBranch recreates branch from its archive tag and removes
+// the tag.
+func restoreArchivedBranch(branch string) {
+	tag := archiveTagPrefix + branch
+	if gitQuiet("rev-parse", "--verify", "--quiet", "refs/tags/"+tag) != nil {
+		warn("No branch %s is archived.", branch)
+		exit(1)
+	}
+	if branchExists(branch) {
+		warn("A branch named %s already exists.", branch)
+		exit(1)
+	}
+	if dryRun {
+		status("Dry run, %s was not restored from the tag %s.", branch, tag)
+		return
+	}
+	if output, err := gitCombinedOutput("branch", branch, "refs/tags/"+tag+"^{commit}"); err != nil {
+		warn("Error creating %s: %s", branch, output)
+		exit(1)
+	}
+	if output, err := gitCombinedOutput("tag", "-d", tag); err != nil {
+		warn("Restored %s but cannot delete the tag %s: %s", branch, tag, output)
+		exit(1)
+	}
+	status("Restored %s and removed the tag %s.", branch, tag)
+}
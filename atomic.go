@@ -0,0 +1,72 @@
+package main
+
+import (
+	"sort"
+)
+
+// deleteBranchesAtomically deletes toDelete, or none of them: if any
+// deletion fails, the branches already deleted are recreated at their
+// recorded tips with their upstreams. It returns how many branches remain
+// deleted.
+func deleteBranchesAtomically(toDelete []string, force bool) int {
+	if dryRun {
+		showDryRunDeletion(toDelete)
+		return 0
+	}
+	refs, err := readBranchRefs()
+	if err != nil {
+		warn("Error recording branch tips, nothing was deleted: %s", err)
+		exit(1)
+	}
+	recorded := make(map[string]branchInfo, len(refs))
+	for _, b := range refs {
+		recorded[b.Name] = b
+	}
+
+	failed := _deleteBranches(toDelete, force)
+	if len(failed) == 0 {
+		status("\nAll %d branches deleted.\n", len(toDelete))
+		return len(toDelete)
+	}
+
+	var failedBranches []string
+	for branch := range failed {
+		failedBranches = append(failedBranches, branch)
+	}
+	sort.Strings(failedBranches)
+	status("\n\nFailed to delete the following branches:")
+	for _, branch := range failedBranches {
+		warn("Branch: %s - Error: %s", branch, failed[branch])
+	}
+
+	title("\nRolling back")
+	var lost, restored []string
+	for _, branch := range toDelete {
+		if _, ok := failed[branch]; ok {
+			continue
+		}
+		b := recorded[branch]
+		if output, err := gitCombinedOutput("branch", b.Name, b.SHA); err != nil {
+			warn("Error restoring %s at %s: %s", b.Name, b.SHA, output)
+			lost = append(lost, b.Name)
+			continue
+		}
+		if b.Upstream != "" {
+			if output, err := gitCombinedOutput("branch", "--set-upstream-to="+b.Upstream, b.Name); err != nil {
+				warn("Restored %s but could not set its upstream to %s: %s", b.Name, b.Upstream, output)
+			}
+		}
+		info("Restored %s at %s", b.Name, shortSHA(b.SHA))
+		restored = append(restored, b.Name)
+	}
+	if currentTrashRun != "" {
+		untrashBranches(currentTrashRun, restored)
+	}
+
+	if len(lost) > 0 {
+		warn("\nRollback incomplete: %d branches could not be restored. Their tips are shown above.", len(lost))
+		return len(lost)
+	}
+	warn("\nNothing was deleted: the operation was rolled back because %d of %d branches could not be deleted.", len(failed), len(toDelete))
+	return 0
+}
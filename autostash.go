@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+)
+
+// switchBranch runs git switch with args. If that fails because of local
+// changes, the changes are stashed, the switch retried and the stash popped
+// again, either directly with autostash or after asking.
+func switchBranch(autostash bool, args ...string) {
+	if err := gitRun(append([]string{"switch"}, args...)...); err == nil {
+		return
+	}
+	if !hasLocalChanges() {
+		exit(1)
+	}
+
+	if !autostash {
+		answer, err := ask("\nStash your changes, switch, and restore them on the new branch? [y/N]")
+		if err != nil || !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+			exit(1)
+		}
+	}
+
+	title("Stashing local changes")
+	if err := gitRun("stash", "push", "--include-untracked", "--message", AppName+" autostash"); err != nil {
+		warn("Error stashing changes.")
+		exit(1)
+	}
+	if err := gitRun(append([]string{"switch"}, args...)...); err != nil {
+		warn("Switch failed, restoring your changes.")
+		if err := gitRun("stash", "pop"); err != nil {
+			warn("Your changes are still in the stash. Run 'git stash pop' to restore them.")
+		}
+		exit(1)
+	}
+	if err := gitRun("stash", "pop"); err != nil {
+		warn("Your changes conflict with this branch. Resolve the conflicts; the stash is kept until you run 'git stash drop'.")
+		exit(1)
+	}
+	status("Changes restored.")
+}
+
+// hasLocalChanges reports whether the working tree has modified or untracked
+// files.
+func hasLocalChanges() bool {
+	lines, err := gitLines("status", "--porcelain")
+	return err == nil && len(lines) > 0
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// backportResult is what happened when a commit was picked onto a branch.
+type backportResult struct {
+	branch string
+	ok     bool
+	note   string
+}
+
+// runBackport cherry-picks a commit onto each named or matching branch.
+// Branches that are not checked out are worked on in a temporary worktree,
+// so the current checkout is left alone.
+func runBackport(args []string) {
+	if len(args) < 2 {
+		usageError("backport")
+	}
+	lines, err := gitLines("rev-parse", "--verify", "--quiet", args[0]+"^{commit}")
+	if err != nil || len(lines) == 0 {
+		warn("No such commit %s.", args[0])
+		exit(1)
+	}
+	commit := lines[0]
+
+	targets, err := backportTargets(args[1:])
+	if err != nil {
+		warn(err.Error())
+		exit(1)
+	}
+	_, checkedOut, err := worktreeList()
+	if err != nil {
+		warn("Error listing worktrees: %s", err)
+		exit(1)
+	}
+
+	subject, _ := gitLines("log", "-1", "--format=%s", commit)
+	title("Backporting %s %s onto %s", shortSHA(commit), strings.Join(subject, ""), branchCount(len(targets)))
+	if dryRun {
+		for _, branch := range targets {
+			info("Would cherry-pick onto %s", branch)
+		}
+		status("Dry run, nothing was cherry-picked.")
+		return
+	}
+	var results []backportResult
+	for _, branch := range targets {
+		result := backport(commit, branch, checkedOut[branch])
+		if result.ok {
+			status("%s: %s", branch, result.note)
+		} else {
+			warn("%s: %s", branch, result.note)
+		}
+		results = append(results, result)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if !r.ok {
+			failed++
+		}
+	}
+	if failed > 0 {
+		warn("\n%d of %s not backported.", failed, branchCount(len(results)))
+		exit(1)
+	}
+}
+
+// backportTargets turns branch names and patterns into the branches to pick
+// onto, in list order.
+func backportTargets(args []string) ([]string, error) {
+	branches, _, err := listBranches()
+	if err != nil {
+		return nil, err
+	}
+	sortBranches(branches)
+
+	var targets []string
+	for _, arg := range args {
+		if contains(branches, arg) {
+			if !contains(targets, arg) {
+				targets = append(targets, arg)
+			}
+			continue
+		}
+		matched := false
+		for _, branch := range branches {
+			if matchesPattern(branch, arg) {
+				matched = true
+				if !contains(targets, branch) {
+					targets = append(targets, branch)
+				}
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no branch is named or matches %s", arg)
+		}
+	}
+	return targets, nil
+}
+
+// backport cherry-picks commit onto branch, in the worktree at dir if the
+// branch is checked out there and otherwise in a temporary one.
+func backport(commit, branch, dir string) backportResult {
+	if gitQuiet("merge-base", "--is-ancestor", commit, "refs/heads/"+branch) == nil {
+		return backportResult{branch, true, "already contains the commit"}
+	}
+
+	if dir != "" {
+		if lines, err := gitLines("-C", dir, "status", "--porcelain", "--untracked-files=no"); err != nil || len(lines) > 0 {
+			return backportResult{branch, false, "skipped, checked out with local changes in " + dir}
+		}
+	} else {
+		tmp, err := os.MkdirTemp("", AppName+"-backport-")
+		if err != nil {
+			return backportResult{branch, false, err.Error()}
+		}
+		dir = filepath.Join(tmp, "worktree")
+		if err := gitQuiet("worktree", "add", "--quiet", dir, branch); err != nil {
+			os.RemoveAll(tmp)
+			return backportResult{branch, false, "cannot add a worktree for it"}
+		}
+		defer func() {
+			_ = gitQuiet("worktree", "remove", "--force", dir)
+			os.RemoveAll(tmp)
+		}()
+	}
+
+	if output, err := gitCombinedOutput("-C", dir, "cherry-pick", "-x", commit); err != nil {
+		conflicts, _ := gitLines("-C", dir, "diff", "--name-only", "--diff-filter=U")
+		_ = gitQuiet("-C", dir, "cherry-pick", "--abort")
+		switch {
+		case len(conflicts) > 0:
+			return backportResult{branch, false, "conflicts in " + describeConflicts(conflicts)}
+		case strings.Contains(string(output), "empty"):
+			// cherry-pick stops when the changes are already there.
+			return backportResult{branch, true, "already has the changes"}
+		}
+		reason, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+		return backportResult{branch, false, reason}
+	}
+	tip, _ := gitLines("-C", dir, "rev-parse", "--short", "HEAD")
+	return backportResult{branch, true, "picked as " + strings.Join(tip, "")}
+}
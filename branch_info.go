@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// branchInfo holds the metadata shown when deciding whether to delete a branch.
+type branchInfo struct {
+	Name     string
+	SHA      string
+	Upstream string
+	// Track is the upstream comparison, such as "ahead 1, behind 2" or
+	// "gone".
+	Track   string
+	Author  string
+	Email   string
+	Date    time.Time
+	Current bool
+	Merged  bool
+	// HistoryMissing is set for unmerged branches in a shallow clone that
+	// share no history with any base branch, so whether they are merged
+	// cannot be told.
+	HistoryMissing bool
+}
+
+// branchRefFormat is the for-each-ref format read by readBranchRefs. Fields
+// are NUL separated so that author names cannot break the parsing.
+const branchRefFormat = "%(HEAD)%00%(refname:short)%00%(objectname)%00%(upstream:short)%00" +
+	"%(upstream:track,nobracket)%00%(committerdate:unix)%00%(authorname)%00%(authoremail)"
+
+// readBranchRefs reads every local branch with its tip, upstream and last
+// commit in a single for-each-ref call. Merged is not filled in.
+func readBranchRefs() ([]branchInfo, error) {
+	lines, err := gitLines("for-each-ref", "--format="+branchRefFormat, "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]branchInfo, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 8 {
+			return nil, fmt.Errorf("unexpected for-each-ref output %q", line)
+		}
+		seconds, err := strconv.ParseInt(fields[5], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected for-each-ref output %q", line)
+		}
+		infos = append(infos, branchInfo{
+			Current:  fields[0] == "*",
+			Name:     fields[1],
+			SHA:      fields[2],
+			Upstream: fields[3],
+			Track:    fields[4],
+			Date:     time.Unix(seconds, 0),
+			Author:   fields[6],
+			Email:    strings.Trim(fields[7], "<>"),
+		})
+	}
+	return infos, nil
+}
+
+// loadBranchInfos looks up the details of each branch and whether it is
+// merged into one of the base branches.
+func loadBranchInfos(branches []string) (map[string]branchInfo, error) {
+	all, err := readBranchRefs()
+	if err != nil {
+		return nil, err
+	}
+	merged, err := mergedIntoAnyBase()
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]branchInfo, len(all))
+	for _, b := range all {
+		byName[b.Name] = b
+	}
+
+	infos := make(map[string]branchInfo, len(branches))
+	for _, branch := range branches {
+		b, ok := byName[branch]
+		if !ok {
+			return nil, fmt.Errorf("no such branch %s", branch)
+		}
+		b.Merged = contains(merged, branch)
+		if !b.Merged && isShallowRepository() {
+			b.HistoryMissing = !sharesHistoryWithBase(branch)
+		}
+		infos[branch] = b
+	}
+	return infos, nil
+}
+
+// formatDuration renders age compactly in whole days, or hours when under a
+// day, as durations are written in the config.
+func formatDuration(age time.Duration) string {
+	if age < 24*time.Hour {
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(age.Hours()/24))
+}
+
+func (b branchInfo) mergedLabel() string {
+	if b.Merged {
+		return "merged"
+	}
+	if b.HistoryMissing {
+		return "unknown"
+	}
+	return "unmerged"
+}
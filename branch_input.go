@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+// readBranchNames reads newline- or NUL-separated branch names from r,
+// accepting full refs/heads/ names as produced by git for-each-ref.
+func readBranchNames(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.FieldsFunc(string(data), func(c rune) bool {
+		return c == '\n' || c == '\r' || c == 0
+	})
+
+	var names []string
+	for _, field := range fields {
+		name := strings.TrimPrefix(strings.TrimSpace(field), "refs/heads/")
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// readBranchFile reads branch names from a file with one branch per line.
+// Blank lines and lines starting with '#' are ignored, as is anything after
+// the first whitespace on a line, so entries can carry trailing comments.
+func readBranchFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		names = append(names, strings.TrimPrefix(fields[0], "refs/heads/"))
+	}
+	return names, scanner.Err()
+}
+
+// deleteNamedBranches deletes the given branches that also satisfy sel,
+// ignoring names that are not local branches.
+func deleteNamedBranches(names []string, sel selection, opts deleteOptions) {
+	branches, currentBranch, err := listBranches()
+	if err != nil {
+		fatal("Error listing branches:", err)
+	}
+
+	var named []string
+	for _, name := range names {
+		if !contains(branches, name) {
+			warn("Ignoring %s: no such branch", name)
+			continue
+		}
+		if !contains(named, name) {
+			named = append(named, name)
+		}
+	}
+
+	toDelete, err := sel.filter(named)
+	if err != nil {
+		fatal("Error selecting branches:", err)
+	}
+
+	confirmAndDeleteBranches(toDelete, currentBranch, opts)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// listBranchesByAuthor prints branches grouped under the author of their tip
+// commit, with per-author counts and the combined age of their branches.
+func listBranchesByAuthor(branches []string) {
+	infos, err := loadBranchInfos(branches)
+	if err != nil {
+		warn("Error reading branch details: %s", err)
+		exit(1)
+	}
+
+	groups := make(map[string][]branchInfo)
+	for _, branch := range branches {
+		b := infos[branch]
+		groups[b.Author] = append(groups[b.Author], b)
+	}
+
+	var authors []string
+	for author := range groups {
+		authors = append(authors, author)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if len(groups[authors[i]]) != len(groups[authors[j]]) {
+			return len(groups[authors[i]]) > len(groups[authors[j]])
+		}
+		return authors[i] < authors[j]
+	})
+
+	for _, author := range authors {
+		group := groups[author]
+		sort.Slice(group, func(i, j int) bool { return group[i].Date.Before(group[j].Date) })
+
+		var staleness time.Duration
+		for _, b := range group {
+			staleness += time.Since(b.Date)
+		}
+
+		branchStr := "branches"
+		if len(group) == 1 {
+			branchStr = "branch"
+		}
+		title("%s: %d %s, %s total staleness", author, len(group), branchStr, humanizeDuration(staleness))
+		for _, b := range group {
+			info("    %s (last commit %s)", b.Name, formatWhen(b.Date))
+		}
+	}
+}
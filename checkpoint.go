@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// checkpoint records the progress of a multi-branch operation so that an
+// interrupted run can be continued with `resume`.
+type checkpoint struct {
+	// Operation is delete for local branches, rtb-delete for
+	// remote-tracking branches or remote-delete for branches on Remote.
+	Operation string    `json:"operation"`
+	Force     bool      `json:"force,omitempty"`
+	Remote    string    `json:"remote,omitempty"`
+	Started   time.Time `json:"started"`
+	Remaining []string  `json:"remaining"`
+}
+
+func checkpointPath() (string, error) {
+	common, err := gitCommonDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(common, AppName+"-checkpoint.json"), nil
+}
+
+// loadCheckpoint returns the saved checkpoint, or nil if there is none.
+func loadCheckpoint() (*checkpoint, error) {
+	path, err := checkpointPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+func saveCheckpoint(cp checkpoint) error {
+	path, err := checkpointPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	// Write then rename so an interruption never leaves half a file.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func clearCheckpoint() {
+	if path, err := checkpointPath(); err == nil {
+		os.Remove(path)
+	}
+}
+
+// processWithCheckpoint calls process on each chunk in turn, keeping cp
+// updated with the items not yet processed until all chunks are done.
+func processWithCheckpoint(cp checkpoint, chunks [][]string, process func([]string)) {
+	if old, err := loadCheckpoint(); err == nil && old != nil {
+		warn("Discarding an interrupted %s of %d branches from %s.", old.Operation, len(old.Remaining), old.Started.Format("2006-01-02 15:04"))
+	}
+
+	cp.Started = time.Now()
+	for _, chunk := range chunks {
+		cp.Remaining = append(cp.Remaining, chunk...)
+	}
+
+	saving := true
+	for _, chunk := range chunks {
+		if saving {
+			if err := saveCheckpoint(cp); err != nil {
+				warn("Cannot save progress, this run cannot be resumed: %s", err)
+				saving = false
+			}
+		}
+		process(chunk)
+		cp.Remaining = cp.Remaining[len(chunk):]
+	}
+	if saving {
+		clearCheckpoint()
+	}
+}
+
+func runResume(args []string) {
+	abandon, args := extractFlag(args, "--abandon")
+	if len(args) != 0 {
+		usageError("resume")
+	}
+
+	cp, err := loadCheckpoint()
+	if err != nil {
+		warn("Error reading checkpoint: %s", err)
+		exit(1)
+	}
+	if cp == nil {
+		status("Nothing to resume.")
+		return
+	}
+	if abandon {
+		clearCheckpoint()
+		status("Abandoned the interrupted %s of %d branches.", cp.Operation, len(cp.Remaining))
+		return
+	}
+
+	title("Resuming %s started %s", cp.Operation, cp.Started.Format("2006-01-02 15:04"))
+	if dryRun {
+		// The checkpoint is kept so that the run can still be resumed.
+		for _, name := range cp.Remaining {
+			info("Would delete %s", name)
+		}
+		status("Dry run, nothing was deleted.")
+		return
+	}
+	switch cp.Operation {
+	case "delete":
+		var remaining []string
+		for _, branch := range cp.Remaining {
+			if branchExists(branch) {
+				remaining = append(remaining, branch)
+			}
+		}
+		// The run below saves a checkpoint of its own.
+		clearCheckpoint()
+		if len(remaining) == 0 {
+			status("No branches left to delete.")
+			return
+		}
+		deleteBranches(remaining, cp.Force)
+	case "rtb-delete":
+		existing, err := listRemoteTrackingBranches()
+		if err != nil {
+			warn("Error listing remote-tracking branches: %s", err)
+			exit(1)
+		}
+		var remaining []string
+		for _, ref := range cp.Remaining {
+			if contains(existing, ref) {
+				remaining = append(remaining, ref)
+			}
+		}
+		clearCheckpoint()
+		if len(remaining) == 0 {
+			status("No remote-tracking branches left to delete.")
+			return
+		}
+		removeRemoteTrackingBranches(remaining)
+	case "remote-delete":
+		// Branches already gone from the remote are skipped by git push
+		// with an error, so check them against the remote first.
+		existing, err := lsRemoteBranches(cp.Remote)
+		if err != nil {
+			warn("Error listing branches on %s: %s", cp.Remote, err)
+			exit(1)
+		}
+		var remaining []string
+		for _, branch := range cp.Remaining {
+			if _, ok := existing[branch]; ok {
+				remaining = append(remaining, branch)
+			}
+		}
+		clearCheckpoint()
+		if len(remaining) == 0 {
+			status("No branches left to delete on %s.", cp.Remote)
+			return
+		}
+		deleteRemoteBranches(cp.Remote, remaining)
+	default:
+		warn("Unknown operation %q in checkpoint. Use '%s resume --abandon' to discard it.", cp.Operation, AppName)
+		exit(1)
+	}
+}
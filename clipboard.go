@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboardCommands lists, per platform, the programs tried in order to put
+// text on the system clipboard.
+func clipboardCommands() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip"}}
+	}
+
+	var commands [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		commands = append(commands, []string{"wl-copy"})
+	}
+	return append(commands,
+		[]string{"xclip", "-selection", "clipboard"},
+		[]string{"xsel", "--clipboard", "--input"},
+		[]string{"clip.exe"},
+	)
+}
+
+// copyToClipboard puts text on the clipboard using the first available
+// clipboard program, falling back to the OSC 52 terminal escape sequence,
+// which also works over SSH in terminals that support it.
+func copyToClipboard(text string) error {
+	for _, command := range clipboardCommands() {
+		if _, err := exec.LookPath(command[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err == nil {
+			return nil
+		}
+	}
+
+	if isTerminal(os.Stdout) {
+		fmt.Printf("\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+		return nil
+	}
+	return errors.New("no clipboard program found (install wl-copy, xclip or xsel)")
+}
+
+func runCopyName(args []string) {
+	if len(args) != 1 {
+		usageError("copy-name")
+	}
+
+	branch, err := resolveBranchArg(args[0])
+	if err != nil {
+		warn(err.Error())
+		exit(1)
+	}
+
+	if err := copyToClipboard(branch); err != nil {
+		warn("Cannot copy to the clipboard: %s", err)
+		exit(1)
+	}
+	info("Copied %s to the clipboard", branch)
+}
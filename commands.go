@@ -0,0 +1,630 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// command is one subcommand of the tool. Its usage text, help and option
+// checking all come from the options declared in flags, so they cannot drift
+// apart.
+type command struct {
+	name string
+	// aliases are shorter names that run the same command.
+	aliases []string
+	// args is the usage text of the arguments other than options.
+	args    string
+	summary string
+	// hidden commands are for the completion scripts or deprecated, and
+	// are left out of usage text and completion.
+	hidden bool
+	// locked commands change branches in several steps and so must not
+	// run at the same time as each other.
+	locked bool
+	// anyArgs commands take arbitrary arguments, which are passed on
+	// without checking them against args.
+	anyArgs bool
+	// noRepo commands also work outside a git repository.
+	noRepo bool
+	// goGit commands also work without the git binary, through go-git.
+	goGit bool
+	// flags are the options the command accepts, and required those of
+	// them it cannot do without.
+	flags    []optionHelp
+	required []string
+	// details and examples complete the command's help.
+	details  string
+	examples []string
+	run      func(args []string)
+}
+
+const globalOptionsUsage = "[--plain] [--theme name] [--absolute-dates] [--log-file path] [--remote name] [--base branch...] [--fetch] [--deepen n] [--no-git-binary] [--dry-run]"
+
+// keepFlags and deleteCommandFlags are the options of keep and delete,
+// shared with their deprecated forms Keep and Delete.
+var keepFlags = append(append([]optionHelp{
+	{"--last n", "also keep the n most recently committed branches"},
+	{"--by-checkout", "with --last, count by most recent checkout instead"},
+	{"--from-file path", "read the branches to keep from a file"},
+}, deleteFlags...), selectionFlags...)
+
+var deleteCommandFlags = append(append([]optionHelp{
+	{"-, --stdin", "read branch names from stdin"},
+	{"--from-file path", "read branch names from a file"},
+}, deleteFlags...), selectionFlags...)
+
+// commands lists every command in the order usage text shows them. It is
+// filled in by init because the run functions refer back to it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{
+			name: "list", aliases: []string{"ls"},
+			args:    "[pattern]",
+			summary: "list local branches",
+			details: "Lists the selected local branches in sorted order. The numbers shown are the indexes other commands accept in place of a branch name.",
+			flags: append([]optionHelp{
+				{"--count", "print only the number of selected branches"},
+				{"--by-author", "group the branches by the author of their last commit"},
+				{"--group-by key", "group the branches by author or by label"},
+				{"--long", "show the tip, upstream, author and date of each branch"},
+				{"--vv", "show each branch like git branch -vv"},
+				{"--remotes", "list remote-tracking branches with their rN indexes"},
+				{"--json", "print the branches as a JSON array"},
+				{"--page n", "show page n of the list"},
+				{"--per-page n", "branches per page (default 50)"},
+			}, selectionFlags...),
+			examples: []string{"list", "list 'feature/*' --merged", "list --newer-than 14d", "list --long --page 2", "list --group-by label", "list --json --merged | jq -r '.[].name'"},
+			run:      runList,
+		},
+		{
+			name: "keep", args: "[branches to keep...]", locked: true,
+			summary:  "delete the selected branches except those named",
+			details:  "Deletes every selected branch that is not named, so naming the branches to keep cleans up everything else. The current and protected branches are never deleted.",
+			flags:    keepFlags,
+			examples: []string{"keep main develop", "keep --last 5", "keep --force --last 3 --by-checkout"},
+			run:      func(args []string) { runKeep(args, false) },
+		},
+		{
+			name: "delete", aliases: []string{"rm"}, locked: true,
+			args:     "[pattern]",
+			summary:  "delete the branches matching a pattern or filters",
+			details:  "Deletes the branches matching a pattern and the selection filters, or the branches named on stdin or in a file. Only merged branches are deleted unless --force is given, in which case the commits that would be lost are shown before confirming.",
+			flags:    deleteCommandFlags,
+			examples: []string{"delete 'tmp/*'", "delete --merged --stale", "delete --older-than 90d", "delete --regex 'feature/JIRA-\\d+'", "delete --force 'spike/*' --record restore.sh", "git branch --merged | " + AppName + " delete -"},
+			run:      func(args []string) { runDelete(args, false) },
+		},
+		{
+			name: "edit", locked: true,
+			args:     "[pattern]",
+			summary:  "choose branches to delete in an editor",
+			details:  "Opens the selected branches in your git editor, where each can be marked to keep, delete, or archive as a tag before deleting.",
+			flags:    append(append([]optionHelp(nil), deleteFlags...), selectionFlags...),
+			examples: []string{"edit", "edit 'feature/*' --merged"},
+			run:      runEdit,
+		},
+		{
+			name: "tui", args: "[pattern]", locked: true,
+			summary:  "pick the branches to delete from a full-screen list",
+			details:  "Shows the selected branches, other than protected ones and the current branch, with checkboxes. Move with the arrow keys or j and k, tick a branch with space, tick or untick all shown with a, copy the name of the branch under the cursor with c, and type / to filter by part of the name. Enter goes on to the usual preview and confirmation; q quits without deleting.",
+			flags:    append(append([]optionHelp(nil), deleteFlags...), selectionFlags...),
+			examples: []string{"tui", "tui 'feature/*' --merged"},
+			run:      runTUI,
+		},
+		{
+			name: "snapshot", locked: true,
+			args:     "save <file> | snapshot restore <file> [pattern]",
+			summary:  "save or restore branch tips",
+			details:  "save writes every branch and its tip to a file. restore recreates the branches in the file, or those matching pattern, that no longer exist.",
+			examples: []string{"snapshot save before.json", "snapshot restore before.json 'feature/*'"},
+			run:      runSnapshot,
+		},
+		{
+			name:    "plan",
+			args:    "[pattern]",
+			summary: "write a deletion plan for review",
+			details: "Writes the branches that would be deleted, with their tips, to a plan file that apply carries out later.",
+			flags: append([]optionHelp{
+				{"--out file", "the plan file to write"},
+				{"--force", "plan a forced deletion of unmerged branches"},
+			}, selectionFlags...),
+			required: []string{"--out"},
+			examples: []string{"plan --merged --out cleanup.json"},
+			run:      runPlan,
+		},
+		{
+			name: "apply", args: "<planfile>", locked: true,
+			summary:  "carry out a deletion plan",
+			details:  "Deletes the branches in a plan written by plan, refusing if any branch has moved since.",
+			examples: []string{"apply cleanup.json"},
+			run:      runApply,
+		},
+		{
+			name:     "foreach-submodule",
+			args:     "-- <command> [args...]",
+			summary:  "run a command in every submodule",
+			details:  "Runs another " + AppName + " command inside every initialized submodule.",
+			examples: []string{"foreach-submodule -- delete --merged"},
+			run:      foreachSubmodule,
+		},
+		{
+			name: "archive", args: "list [pattern] | archive restore <name>", locked: true,
+			summary:  "list or restore branches archived as tags",
+			details:  "Branches archived from edit are kept as " + archiveTagPrefix + "<name> tags. list shows them with when they were archived and their last commit, optionally only those matching a pattern. restore recreates the branch at the tag and removes the tag.",
+			examples: []string{"archive list", "archive list 'feature/*'", "archive restore feature/login"},
+			run:      runArchive,
+		},
+		{
+			name: "remote", args: "list [pattern] | remote delete <pattern> | remote keep <pattern>...", locked: true,
+			summary:  "list or delete branches on the remote",
+			details:  "Works on the branches of the remote (origin, or the one given with --remote) as last fetched; add --fetch to update them first. list shows them with their last commit. delete removes the branches matching the pattern from the remote with git push --delete, and keep removes all but those matching one of the patterns. Protected and base branches are never deleted, and the branches are shown for confirmation first.",
+			examples: []string{"remote list 'feature/*'", "--fetch remote delete 'tmp/*'", "remote keep 'release/*' 'feature/*'"},
+			run:      runRemote,
+		},
+		{
+			name: "rtb", args: "delete <pattern>", locked: true,
+			summary:  "delete remote-tracking branches",
+			details:  "Deletes local remote-tracking branches matching pattern without touching the remote.",
+			examples: []string{"rtb delete 'origin/tmp/*'"},
+			run:      runRemoteTrackingBranches,
+		},
+		{
+			name: "prune-remote", locked: true, goGit: true,
+			summary: "delete old branches on the remote",
+			details: "Lists the branches on the remote whose last commit is older than age. Nothing is deleted until the same command is run again with --apply.",
+			flags: []optionHelp{
+				{"--older-than age", "minimum age of the last commit, e.g. 90d"},
+				{"--remote name", "the remote to prune"},
+				{"--apply", "delete the branches listed by the last dry run"},
+			},
+			required: []string{"--older-than"},
+			examples: []string{"prune-remote --older-than 180d", "prune-remote --older-than 180d --apply"},
+			run:      runPruneRemote,
+		},
+		{
+			name: "release", args: "cut <version>", locked: true,
+			summary: "cut a release branch",
+			details: "Creates release/<version> from the base branch, tags the branch point and protects the branch.",
+			flags: []optionHelp{
+				{"--push", "push the branch and tag to the remote"},
+				{"--no-tag", "do not create a tag"},
+			},
+			examples: []string{"release cut 1.4.0 --push"},
+			run:      runRelease,
+		},
+		{
+			name: "feature", args: "start|finish <name>", locked: true,
+			summary: "start or finish a feature branch",
+			details: "start creates a feature branch from the base branch. finish integrates it into the base branch and deletes it.",
+			flags: []optionHelp{
+				{"--base branch", "the branch to start from and finish into"},
+				{"--merge", "finish with a merge commit"},
+				{"--rebase", "finish by rebasing and fast-forwarding"},
+				{"--none", "finish without integrating"},
+				{"--keep-remote", "do not delete the branch on the remote"},
+			},
+			examples: []string{"feature start login", "feature finish login --rebase"},
+			run:      runFeature,
+		},
+		{
+			name:    "check",
+			summary: "report branches that break the configured policies",
+			details: "Checks the repository against the policies in the configuration: max_branches, max_age, required_prefixes and require_upstream. Each violation is reported as an error or a warning, as set under policies.severity, and the status is 1 if there are errors. With --ci, the result is printed as JSON and the status is 1 only if there are more errors or warnings than policies.ci.max_errors (default 0) and policies.ci.max_warnings (default no limit) allow.",
+			flags: []optionHelp{
+				{"--ci", "print the result as JSON and fail only past the configured thresholds"},
+			},
+			examples: []string{"check", "check --ci > branch-policy.json"},
+			run:      runCheck,
+		},
+		{
+			name:     "suggest",
+			summary:  "suggest commands to clean up branches",
+			details:  "Looks for merged branches, unmerged branches whose upstream is gone, stale branches and policy violations, and suggests a command for each. Set list_suggestions: true in the config to show the suggestions after list too.",
+			examples: []string{"suggest"},
+			run:      runSuggest,
+		},
+		{
+			name: "graph", args: "[pattern]",
+			summary: "print a graph of which branches were forked from which",
+			details: "Compares the selected branches, and the base branches, with each other to work out which branch each was forked from, and prints the result as a Graphviz DOT graph (the default) or a Mermaid flowchart. Each edge shows the commits made on the child (+) and on the parent (-) since the fork. Every pair of branches is compared, so narrow the selection in large repositories.",
+			flags: append([]optionHelp{
+				{"--dot", "print Graphviz DOT (the default)"},
+				{"--mermaid", "print a Mermaid flowchart"},
+			}, selectionFlags...),
+			examples: []string{"graph | dot -Tsvg > branches.svg", "graph --mermaid 'feature/*'"},
+			run:      runGraph,
+		},
+		{
+			name: "conflicts", args: "[pattern]",
+			summary:  "forecast which branches would merge cleanly",
+			details:  "Merges each selected branch into the base branch in memory, with git merge-tree, and reports whether it would merge cleanly or which files would conflict. Nothing in the working tree or the repository changes. Useful for deciding which stale branches are worth salvaging. Needs git 2.38 or later.",
+			flags:    selectionFlags,
+			examples: []string{"conflicts --stale", "conflicts 'feature/*'"},
+			run:      runConflicts,
+		},
+		{
+			name:    "report",
+			summary: "write a branch hygiene report, or email it",
+			details: "Reports the branches that are merged, have lost their upstream or are stale, the policy violations and the suggested cleanup, as Markdown or with --html as an HTML page. With --email, which may be given more than once, the report is sent to the address instead, with both forms in one message. Mail goes through the server in report.smtp (host, port, username, from); the password is read from " + strings.ToUpper(AppName) + "_SMTP_PASSWORD or stored with '" + AppName + " auth login smtp'. Meant for scheduled runs on shared build servers.",
+			flags: []optionHelp{
+				{"--html", "write the report as an HTML page"},
+				{"--email address", "send the report to address; repeat for several"},
+			},
+			examples: []string{"report > hygiene.md", "report --email ops@example.com"},
+			run:      runReport,
+		},
+		{
+			name:     "stats",
+			summary:  "show branch statistics",
+			details:  "Shows how often and how recently each branch was checked out, from the reflog.",
+			flags:    []optionHelp{{"--checkouts", "count checkouts of each branch"}},
+			required: []string{"--checkouts"},
+			examples: []string{"stats --checkouts"},
+			run:      runStats,
+		},
+		{
+			name: "merged", args: "[base|index]", locked: true,
+			summary:  "list or delete branches merged into a base branch",
+			details:  "Lists the local branches fully merged into the base branch, the first configured one unless another is given, leaving out the base itself and the current branch. With --delete, they are deleted as by delete, with the same confirmation and flags; protected branches are skipped.",
+			flags:    append([]optionHelp{{"--delete", "delete the merged branches"}}, deleteFlags...),
+			examples: []string{"merged", "merged develop --delete"},
+			run:      runMerged,
+		},
+		{
+			name: "merged-into", args: "<branch|index|tag|commit>",
+			summary:  "list branches merged into a commit",
+			details:  "Lists the local branches whose tips are reachable from the given branch, tag or commit.",
+			examples: []string{"merged-into v1.2.0"},
+			run:      runMergedInto,
+		},
+		{
+			name: "maintenance", args: "pack-refs",
+			summary:  "run repository maintenance",
+			details:  "pack-refs packs loose branch refs into the packed-refs file.",
+			examples: []string{"maintenance pack-refs"},
+			run:      runMaintenanceCommand,
+		},
+		{
+			name: "undo", locked: true,
+			summary:  "recreate the branches removed by the last deletion",
+			details:  "Every deletion keeps the tips of the deleted branches under refs/" + AppName + "/trash/ until 'expire' removes them. undo recreates the branches of the latest deletion at the commits they pointed to.",
+			examples: []string{"undo"},
+			run:      runUndo,
+		},
+		{
+			name: "restore", args: "[branch]", locked: true,
+			summary:  "recreate a deleted branch from the trash",
+			details:  "Recreates branch at the commit it pointed to when it was last deleted. Without a branch, lists the deleted branches that can be restored.",
+			examples: []string{"restore", "restore feature/login"},
+			run:      runRestore,
+		},
+		{
+			name: "expire", locked: true,
+			summary: "drop old trash and reflog entries of deleted work",
+			details: "Empties the trash of branches deleted before the cutoff and expires reflog entries for unreachable commits, so the work of deleted branches can be garbage collected.",
+			flags: []optionHelp{
+				{"--older-than age", "only entries older than age (default 30d)"},
+				{"--dry-run", "show what would be expired"},
+			},
+			examples: []string{"expire --older-than 60d --dry-run"},
+			run:      runExpire,
+		},
+		{
+			name: "resume", locked: true, goGit: true,
+			summary:  "finish or abandon an interrupted operation",
+			details:  "Continues a deletion that was interrupted, from the checkpoint it left behind.",
+			flags:    []optionHelp{{"--abandon", "discard the checkpoint instead"}},
+			examples: []string{"resume", "resume --abandon"},
+			run:      runResume,
+		},
+		{
+			name: "open", args: "<branch|index>",
+			summary: "open a branch on the hosting service",
+			details: "Opens the branch's page, pull request or comparison with the base branch in a browser.",
+			flags: []optionHelp{
+				{"--branch", "open the branch (the default)"},
+				{"--pr", "open the branch's pull request"},
+				{"--compare", "open a comparison with the base branch"},
+				{"--print", "print the URL instead of opening it"},
+			},
+			examples: []string{"open 3 --pr"},
+			run:      runOpen,
+		},
+		{
+			name: "copy-name", args: "<index|branch>",
+			summary:  "copy a branch name to the clipboard",
+			examples: []string{"copy-name 4"},
+			run:      runCopyName,
+		},
+		{
+			name: "switch", aliases: []string{"co"}, args: "<branch|index|rN|hN|->",
+			summary: "switch to a branch",
+			details: "Switches to a branch given by name, list index, remote index rN, recent index hN, or - for the previous branch. Switching to a remote branch creates a local branch that tracks it.",
+			flags: []optionHelp{
+				{"--as local-name", "name for the local branch created from a remote branch"},
+				{"--autostash", "stash local changes before switching and restore them after"},
+				{"--worktree", "change to the branch's worktree, adding one if needed; needs shell-init"},
+			},
+			examples: []string{"switch 3", "switch r2 --as fix", "switch -", "switch 4 --worktree"},
+			run:      runSwitch,
+		},
+		{
+			name:    "-",
+			summary: "switch to the previous branch",
+			flags: []optionHelp{
+				{"--autostash", "stash local changes before switching and restore them after"},
+				{"--worktree", "change to the branch's worktree, adding one if needed; needs shell-init"},
+			},
+			examples: []string{"-"},
+			run:      func(args []string) { runSwitch(append([]string{"-"}, args...)) },
+		},
+		{
+			name:     "recent",
+			summary:  "list recently checked out branches",
+			details:  "Lists the branches you checked out most recently, with the hN indexes other commands accept.",
+			flags:    []optionHelp{{"-n count", "how many branches to list (default 10)"}},
+			examples: []string{"recent -n 5"},
+			run:      runRecent,
+		},
+		{
+			name: "label", args: "[branch [text...]]",
+			summary: "label branches with notes",
+			details: "Adds a free-form label to a branch, such as why it is kept. Labels show in list and can be selected with --label. With only a branch, shows its labels; with nothing, lists every labeled branch. Labels are kept in the branch's git config, so they follow renames and go when the branch is deleted.",
+			flags: []optionHelp{
+				{"--remove", "remove the label text from the branch"},
+				{"--clear", "remove all of the branch's labels"},
+			},
+			examples: []string{"label demo/acme 'demo for ACME, keep until Q3'", "label demo/acme", "list --label 'demo*'"},
+			run:      runLabel,
+		},
+		{
+			name: "stack", args: "list | stack set-parent <branch> <parent> | stack restack <top>", locked: true,
+			summary:  "show or rebase stacks of branches built on each other",
+			details:  "list shows the branches stacked on other local branches rather than on a base branch. A branch is stacked on the parent recorded with set-parent, else on the local branch it tracks, or else on the branch it was forked from. set-parent records the branch one is stacked on in its git config, for when the fork can no longer be worked out, such as after the parent was rebased. restack records the parents it found, then rebases every branch from the bottom of top's stack up to top onto the current tip of the branch below it, for after the bottom was amended or rebased.",
+			examples: []string{"stack list", "stack set-parent feature/part-2 feature/part-1", "stack restack feature/part-3"},
+			run:      runStack,
+		},
+		{
+			name: "auth", args: "login|logout github|smtp [host] | status", noRepo: true,
+			summary:  "keep integration tokens in the OS keychain",
+			details:  "Stores the GitHub API token for a host, or the password of the report mail server, in the macOS Keychain, the Windows Credential Manager or the Secret Service keyring (through secret-tool), so that it need not sit in a config file or the environment. login asks for the token without echoing it, or reads it from stdin when piped. The host defaults to the remote's host for github and to report.smtp.host for smtp. status shows where each token is currently read from: the config, the environment, the keychain or the gh CLI.",
+			examples: []string{"auth login github", "auth login github github.example.com", "pass show smtp | gbm auth login smtp", "auth status"},
+			run:      runAuth,
+		},
+		{
+			name: "backport", args: "<commit> <branch|pattern>...", locked: true,
+			summary:  "cherry-pick a commit onto several branches",
+			details:  "Cherry-picks the commit, recording where it came from, onto each named branch and each branch matching a pattern. Branches that are not checked out are worked on in a temporary worktree, so your checkout is left alone; a branch checked out with local changes is skipped. A branch whose pick conflicts is left as it was, and the conflicting files are reported.",
+			examples: []string{"backport a1b2c3d 'release/*'", "backport HEAD release/2.3 release/2.4"},
+			run:      runBackport,
+		},
+		{
+			name: "rename", aliases: []string{"mv"}, args: "<branch|index|hN> <new-name>",
+			summary:  "rename a branch",
+			examples: []string{"rename 2 feature/login"},
+			run:      runRename,
+		},
+		{
+			name:    "new",
+			args:    "<name>",
+			summary: "create a branch",
+			details: "Creates a branch from the base branch, or from the given start point.",
+			flags: []optionHelp{
+				{"--from branch|index|rN|sha", "the branch, index, remote index or commit to start from"},
+				{"--upstream none|from|inherit|push", "none, from (track the start point), inherit (copy its upstream) or push"},
+				{"--switch", "switch to the new branch"},
+			},
+			examples: []string{"new fix/login --from r3 --switch"},
+			run:      runNew,
+		},
+		{
+			name: "init", noRepo: true,
+			summary: "set up completion and a config file, step by step",
+			details: "Asks whether to install tab completion for your shell, which branch work is merged into, which branches to protect and whether to look up GitHub pull requests, then writes the answers to the global config file, or to this repository's " + repoConfigFile + ". Settings already in the file are kept.",
+			run:     runInit,
+		},
+		{
+			name: "generate-completion", args: "bash|zsh|fish|elvish", noRepo: true,
+			summary:  "print a shell completion script",
+			examples: []string{"generate-completion bash > ~/.local/share/bash-completion/completions/" + AppName},
+			run:      generateCompletion,
+		},
+		{
+			name:    "mcp",
+			summary: "serve branch tools to editors and AI assistants",
+			details: "Runs a Model Context Protocol server on stdin and stdout, offering the tools list_branches, stale_branches and plan_deletion. Configure your editor or assistant to start '" + AppName + " mcp' in the repository. The server never deletes branches: plan_deletion writes a plan, as a new file in the repository's " + AppName + "-plans directory, for the user to review and carry out with apply.",
+			run:     runMCP,
+		},
+		{
+			name: "completion", args: "check [bash|zsh|fish|elvish]", noRepo: true,
+			summary:  "check that shell completion works",
+			details:  "Checks that " + AppName + " is on your PATH, that the completion script is installed and current, that your shell has it registered, and that it returns branches, and says how to fix whatever is wrong. The shell defaults to $SHELL.",
+			examples: []string{"completion check", "completion check zsh"},
+			run:      runCompletion,
+		},
+		{
+			name: "shell-init", args: "bash|zsh|fish", noRepo: true,
+			summary: "print a shell function that lets commands change directory",
+			details: "Prints a wrapper function for " + AppName + ". Load it from your shell's startup file so that commands such as switch --worktree can change the shell's directory.",
+			examples: []string{
+				`eval "$(` + AppName + ` shell-init bash)"`,
+				AppName + " shell-init fish | source",
+			},
+			run: runShellInit,
+		},
+		{
+			name: "help", args: "[command]", noRepo: true,
+			summary:  "show help for a command",
+			examples: []string{"help delete"},
+			run:      runHelp,
+		},
+		{
+			name: "complete-branches", args: "[command]", hidden: true, anyArgs: true, noRepo: true,
+			run: completeBranches,
+		},
+		// Keep and Delete were the original way to force and stay so that
+		// existing scripts keep working.
+		{
+			name: "Keep", args: "[branches to keep...]", flags: keepFlags, hidden: true, locked: true,
+			run: func(args []string) {
+				warnDeprecated("Keep", "keep --force")
+				runKeep(args, true)
+			},
+		},
+		{
+			name: "Delete", hidden: true, locked: true,
+			args: "[pattern]", flags: deleteCommandFlags,
+			run: func(args []string) {
+				warnDeprecated("Delete", "delete --force")
+				runDelete(args, true)
+			},
+		},
+	}
+}
+
+// warnDeprecated tells the user that the command old is deprecated in
+// favour of replacement.
+func warnDeprecated(old, replacement string) {
+	warn("'%s %s' is deprecated; use '%s %s' instead.", AppName, old, AppName, replacement)
+}
+
+// findCommand returns the command called name, or with name as an alias.
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name || contains(c.aliases, name) {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// visibleCommands returns the names of the commands shown to users.
+func visibleCommands() []string {
+	var names []string
+	for _, c := range commands {
+		if !c.hidden {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
+
+// commandWords returns the names and aliases of the visible commands, for
+// completion.
+func commandWords() []string {
+	var words []string
+	for _, c := range commands {
+		if !c.hidden {
+			words = append(words, c.name)
+			words = append(words, c.aliases...)
+		}
+	}
+	return words
+}
+
+// label is the command's name followed by any aliases, as usage text shows
+// it.
+func (c command) label() string {
+	if len(c.aliases) == 0 {
+		return c.name
+	}
+	return c.name + " (" + strings.Join(c.aliases, ", ") + ")"
+}
+
+// runCommand checks args against the command's options and runs it.
+func runCommand(c command, args []string) {
+	if !c.anyArgs {
+		if wantsHelp(args) {
+			showHelp(os.Stdout, c)
+			return
+		}
+		if err := c.checkOptions(args); err != nil {
+			fatalf("%s\n%s", err, c.usage())
+		}
+	}
+	c.run(args)
+}
+
+func (c command) usage() string {
+	parts := []string{"Usage:", AppName, c.name}
+	if c.args != "" {
+		parts = append(parts, c.args)
+	}
+	for _, f := range c.flags {
+		names, _ := f.spellings()
+		parts = append(parts, f.usage(contains(c.required, names[len(names)-1])))
+	}
+	return strings.Join(parts, " ")
+}
+
+// usageError exits showing the usage of the command called name.
+func usageError(name string) {
+	c, _ := findCommand(name)
+	fatal(c.usage())
+}
+
+// options maps each spelling of the command's options to whether it takes
+// a value.
+func (c command) options() map[string]bool {
+	options := make(map[string]bool)
+	for _, f := range c.flags {
+		names, placeholder := f.spellings()
+		for _, name := range names {
+			options[name] = placeholder != ""
+		}
+	}
+	return options
+}
+
+// checkOptions reports the first argument that looks like an option the
+// command does not accept. Everything after "--" is left alone.
+func (c command) checkOptions(args []string) error {
+	options := c.options()
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if arg == "-" || !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, _, hasValue := strings.Cut(arg, "=")
+		takesValue, ok := options[name]
+		if !ok {
+			return fmt.Errorf("Unknown option %s for %s", name, c.name)
+		}
+		if takesValue && !hasValue {
+			i++
+		}
+	}
+	return nil
+}
+
+// showUsage prints the tool's usage, with every visible command and its
+// summary, to w.
+func showUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s %s <command> [args]\n\nCommands:\n", AppName, globalOptionsUsage)
+	t := table{}
+	for _, c := range commands {
+		if !c.hidden {
+			t.addRow(c.label(), c.summary)
+		}
+	}
+	for _, line := range t.lines() {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+	fmt.Fprintf(w, "\nRun '%s help <command>' for details of a command.\n", AppName)
+}
+
+// quotedCommandList names the visible commands for error messages, as
+// 'a', 'b' or 'c'.
+func quotedCommandList() string {
+	names := visibleCommands()
+	for i, name := range names {
+		names[i] = "'" + name + "'"
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestCommandFlagsAreWellFormed(t *testing.T) {
+	for _, c := range commands {
+		options := c.options()
+		for _, f := range c.flags {
+			if names, _ := f.spellings(); len(names) == 0 {
+				t.Errorf("%s: option %q has no spelling", c.name, f.name)
+			}
+		}
+		for _, name := range c.required {
+			if _, ok := options[name]; !ok {
+				t.Errorf("%s: required option %s is not declared", c.name, name)
+			}
+		}
+	}
+}
+
+func TestCheckOptions(t *testing.T) {
+	list, _ := findCommand("list")
+	tests := []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"feature/*", "--merged"}, true},
+		{[]string{"-i", "--older-than", "30d"}, true},
+		{[]string{"--older-than=30d"}, true},
+		// The value of an option is not checked as an option itself.
+		{[]string{"--author", "-x"}, true},
+		{[]string{"--", "--bogus"}, true},
+		{[]string{"--bogus"}, false},
+		{[]string{"--apply"}, false},
+	}
+	for _, tt := range tests {
+		if err := list.checkOptions(tt.args); (err == nil) != tt.ok {
+			t.Errorf("checkOptions(%q) = %v, want ok %v", tt.args, err, tt.ok)
+		}
+	}
+}
+
+func TestOptionUsage(t *testing.T) {
+	tests := []struct {
+		option   optionHelp
+		required bool
+		want     string
+	}{
+		{optionHelp{"--merged", ""}, false, "[--merged]"},
+		{optionHelp{"-i, --ignore-case", ""}, false, "[-i|--ignore-case]"},
+		{optionHelp{"--older-than age", ""}, false, "[--older-than age]"},
+		{optionHelp{"--out file", ""}, true, "--out file"},
+		{optionHelp{"-, --stdin", ""}, false, "[-|--stdin]"},
+	}
+	for _, tt := range tests {
+		if got := tt.option.usage(tt.required); got != tt.want {
+			t.Errorf("usage(%q) = %q, want %q", tt.option.name, got, tt.want)
+		}
+	}
+}
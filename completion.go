@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+var completionScripts = map[string]string{
+	"bash": `_{{app}}() {
+    local cur=${COMP_WORDS[COMP_CWORD]}
+    if [ "$COMP_CWORD" -eq 1 ]; then
+        COMPREPLY=($(compgen -W "{{commands}}" -- "$cur"))
+    else
+        COMPREPLY=($(compgen -W "$({{app}} complete-branches "${COMP_WORDS[1]}" 2>/dev/null)" -- "$cur"))
+    fi
+}
+complete -o nosort -F _{{app}} {{app}} 2>/dev/null || complete -F _{{app}} {{app}}
+`,
+	"zsh": `#compdef {{app}}
+_{{app}}() {
+    if (( CURRENT == 2 )); then
+        compadd -- {{commands}}
+    else
+        compadd -V branches -- ${(f)"$({{app}} complete-branches "${words[2]}" 2>/dev/null)"}
+    fi
+}
+compdef _{{app}} {{app}}
+`,
+	"fish": `complete -c {{app}} -f
+complete -c {{app}} -n '__fish_use_subcommand' -a '{{commands}}'
+complete -c {{app}} -n 'not __fish_use_subcommand' -k -a '({{app}} complete-branches (commandline -opc)[2] 2>/dev/null)'
+`,
+	"elvish": `set edit:completion:arg-completer[{{app}}] = {|@words|
+    if (== (count $words) 2) {
+        put {{commands}}
+    } else {
+        {{app}} complete-branches $words[1] 2>/dev/null | from-lines
+    }
+}
+`,
+}
+
+// generateCompletion prints the completion script for shell.
+func generateCompletion(args []string) {
+	if len(args) != 1 || completionScripts[args[0]] == "" {
+		usageError("generate-completion")
+	}
+
+	fmt.Print(completionScript(args[0]))
+}
+
+// completionScript returns the completion script for shell, filled in for
+// this version's commands.
+func completionScript(shell string) string {
+	return strings.NewReplacer(
+		"{{app}}", AppName,
+		"{{commands}}", strings.Join(commandWords(), " "),
+	).Replace(completionScripts[shell])
+}
+
+// completeBranches prints one local branch name per line for shell
+// completion scripts, most recently checked out first, then the rest in
+// list order. args holds the command being completed, if known; switch
+// also offers "-" for the previous branch.
+func completeBranches(args []string) {
+	branches, _, err := listBranches()
+	if err != nil {
+		return
+	}
+	if len(args) > 0 && isSwitchCommand(args[0]) {
+		if _, err := previousBranch(); err == nil {
+			fmt.Println("-")
+		}
+	}
+
+	recent, _ := recentBranches(len(branches))
+	for _, branch := range recent {
+		fmt.Println(branch)
+	}
+	sortBranches(branches)
+	for _, branch := range branches {
+		if !contains(recent, branch) {
+			fmt.Println(branch)
+		}
+	}
+}
+
+// isSwitchCommand reports whether name runs switch, directly or as an alias.
+func isSwitchCommand(name string) bool {
+	c, ok := findCommand(name)
+	return ok && c.name == "switch"
+}
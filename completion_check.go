@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// shellCheckTimeout bounds each run of the user's shell, whose startup files
+// may do anything.
+const shellCheckTimeout = 10 * time.Second
+
+// completionFixes tells the user how to install the completion script for
+// each shell.
+var completionFixes = map[string][]string{
+	"bash": {
+		"mkdir -p ~/.local/share/bash-completion/completions",
+		"{{app}} generate-completion bash > ~/.local/share/bash-completion/completions/{{app}}",
+		"This needs the bash-completion package. Without it, add this line to ~/.bashrc instead:",
+		`eval "$({{app}} generate-completion bash)"`,
+	},
+	"zsh": {
+		"mkdir -p ~/.zfunc",
+		"{{app}} generate-completion zsh > ~/.zfunc/_{{app}}",
+		"Then add these lines to ~/.zshrc, before any other use of compinit:",
+		"fpath=(~/.zfunc $fpath)",
+		"autoload -Uz compinit && compinit",
+	},
+	"fish": {
+		"mkdir -p ~/.config/fish/completions",
+		"{{app}} generate-completion fish > ~/.config/fish/completions/{{app}}.fish",
+	},
+	"elvish": {
+		"Add this line to ~/.config/elvish/rc.elv:",
+		"eval ({{app}} generate-completion elvish | slurp)",
+	},
+}
+
+// runCompletion carries out the completion subcommands. check verifies that
+// completion works in the user's shell, which by default is $SHELL.
+func runCompletion(args []string) {
+	if len(args) == 0 || len(args) > 2 || args[0] != "check" {
+		usageError("completion")
+	}
+	shell := filepath.Base(os.Getenv("SHELL"))
+	if len(args) == 2 {
+		shell = args[1]
+	}
+	if completionScripts[shell] == "" {
+		warn("Completion is not available for %q. Name one of bash, zsh, fish or elvish.", shell)
+		exit(1)
+	}
+
+	title("Checking %s completion for %s", shell, AppName)
+	ok := checkCompletionBinary()
+	ok = checkCompletionFiles(shell) && ok
+	ok = checkCompletionRegistered(shell) && ok
+	ok = checkCompletionBranches() && ok
+	if !ok {
+		exit(1)
+	}
+	status("Completion for %s looks right. Open a new shell if tab still does nothing.", shell)
+}
+
+// checkCompletionBinary checks that the shell finds this program by name,
+// since the completion scripts call it to list branches.
+func checkCompletionBinary() bool {
+	path, err := exec.LookPath(AppName)
+	if err != nil {
+		warn("%s is not on your PATH, so the completion script cannot run it.", AppName)
+		info("    Add the directory holding %s to PATH.", AppName)
+		return false
+	}
+	self, err := os.Executable()
+	if err == nil && !sameFile(path, self) {
+		warn("The %s on your PATH is %s, not this one (%s).", AppName, path, self)
+		info("    Completion uses the commands and branches of that version. Remove or update it.")
+		return false
+	}
+	status("%s is on your PATH at %s.", AppName, path)
+	return true
+}
+
+// checkCompletionFiles looks for the completion script in the places the
+// shell loads it from and checks that it matches this version.
+func checkCompletionFiles(shell string) bool {
+	want := completionScript(shell)
+	for _, path := range completionFiles(shell) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if string(data) != want {
+			warn("%s was generated by another version of %s.", path, AppName)
+			info("    Regenerate it: %s generate-completion %s > %s", AppName, shell, path)
+			return false
+		}
+		status("Completion script installed at %s.", path)
+		return true
+	}
+	if shell == "elvish" || shell == "bash" {
+		// Both are often set up with eval in the startup file instead,
+		// which the registration check covers.
+		info("No completion file found; checking the shell's startup files instead.")
+	} else {
+		info("No completion file found for %s.", shell)
+	}
+	return true
+}
+
+// completionFiles returns the files shell may load the completion script
+// from.
+func completionFiles(shell string) []string {
+	home, _ := os.UserHomeDir()
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+
+	switch shell {
+	case "bash":
+		return []string{
+			filepath.Join(dataHome, "bash-completion", "completions", AppName),
+			filepath.Join("/usr/local/share/bash-completion/completions", AppName),
+			filepath.Join("/usr/share/bash-completion/completions", AppName),
+			filepath.Join("/etc/bash_completion.d", AppName),
+		}
+	case "zsh":
+		output, err := runShell(shell, "print -rl -- $fpath")
+		if err != nil {
+			return nil
+		}
+		var files []string
+		for _, dir := range strings.Split(strings.TrimSpace(output), "\n") {
+			files = append(files, filepath.Join(dir, "_"+AppName))
+		}
+		return files
+	case "fish":
+		return []string{filepath.Join(configHome, "fish", "completions", AppName+".fish")}
+	}
+	return nil
+}
+
+// checkCompletionRegistered starts an interactive shell, so that its startup
+// files run as they would for the user, and asks it for the completion
+// registered for this program.
+func checkCompletionRegistered(shell string) bool {
+	script := map[string]string{
+		// bash-completion loads completions on first use, so load ours
+		// before asking for it.
+		"bash":   "type _completion_loader >/dev/null 2>&1 && _completion_loader {{app}}; complete -p {{app}}",
+		"zsh":    "print -r -- ${_comps[{{app}}]}",
+		"fish":   "complete -C '{{app}} '",
+		"elvish": "put (has-key $edit:completion:arg-completer {{app}})",
+	}[shell]
+	output, err := runShell(shell, strings.ReplaceAll(script, "{{app}}", AppName))
+
+	var registered bool
+	switch shell {
+	case "bash":
+		registered = err == nil && strings.Contains(output, "-F _"+AppName)
+	case "zsh":
+		registered = err == nil && strings.TrimSpace(output) == "_"+AppName
+	case "fish":
+		registered = err == nil && strings.Contains(output, "list")
+	case "elvish":
+		registered = err == nil && strings.TrimSpace(output) == "$true"
+	}
+	if registered {
+		status("%s has completion for %s registered.", shell, AppName)
+		return true
+	}
+
+	if errors := strings.TrimSpace(output); err != nil && errors != "" && shell != "bash" {
+		warn("Running %s failed: %s", shell, errors)
+	}
+	if shell == "zsh" && err == nil && strings.TrimSpace(output) == "" {
+		if compinit, _ := runShell(shell, "print -r -- ${+_comps}"); strings.TrimSpace(compinit) == "0" {
+			warn("zsh completion is not enabled, so no completion scripts load.")
+			info("    Add this line to ~/.zshrc:")
+			info("    autoload -Uz compinit && compinit")
+			return false
+		}
+	}
+	warn("%s has no completion for %s registered. To install it, run:", shell, AppName)
+	for _, line := range completionFixes[shell] {
+		info("    %s", strings.ReplaceAll(line, "{{app}}", AppName))
+	}
+	info("    Then open a new shell.")
+	return false
+}
+
+// checkCompletionBranches runs the command the completion scripts use to
+// list branches and checks that it returns some.
+func checkCompletionBranches() bool {
+	if _, err := gitLines("rev-parse", "--git-dir"); err != nil {
+		info("Not in a git repository; run the check inside one to test branch completion.")
+		return true
+	}
+	path, err := exec.LookPath(AppName)
+	if err != nil {
+		return false
+	}
+	output, err := exec.Command(path, "complete-branches", "switch").Output()
+	if err != nil || len(bytes.TrimSpace(output)) == 0 {
+		warn("'%s complete-branches' returned no branches.", AppName)
+		info("    Run it yourself to see the error, and check that the %s on your PATH is up to date.", AppName)
+		return false
+	}
+	status("Branch completion returns %d branches.", len(strings.Split(strings.TrimSpace(string(output)), "\n")))
+	return true
+}
+
+// runShell runs script in an interactive shell and returns what it printed.
+func runShell(shell, script string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), shellCheckTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, shell, "-i", "-c", script)
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+// sameFile reports whether two paths name the same file, following links.
+func sameFile(a, b string) bool {
+	aInfo, errA := os.Stat(a)
+	bInfo, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(aInfo, bInfo)
+}
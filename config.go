@@ -0,0 +1,327 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// repoConfigFile is the per-repository config file, read from the top of the
+// working tree and layered over the user's global config.
+const repoConfigFile = "." + AppName + ".yaml"
+
+// Config holds the user's settings. Zero values are replaced by defaults in
+// defaultConfig before any file is read.
+type Config struct {
+	// Base lists the branches that merged checks compare against, given as
+	// one name or a list. Empty means the branch the remote's HEAD points
+	// at, or main or master.
+	Base stringList `yaml:"base"`
+	// Protected lists branch patterns that must never be deleted.
+	Protected []string `yaml:"protected"`
+
+	Feature  FeatureConfig `yaml:"feature"`
+	Policies PolicyConfig  `yaml:"policies"`
+	// Identities lists extra author names or emails that count as the
+	// current user for --mine.
+	Identities []string `yaml:"identities"`
+	// Filters are saved selections usable as @name in list, delete and keep.
+	Filters     map[string]namedFilter `yaml:"filters"`
+	Hosting     HostingConfig          `yaml:"hosting"`
+	NewBranch   NewBranchConfig        `yaml:"new_branch"`
+	Maintenance MaintenanceConfig      `yaml:"maintenance"`
+	Notify      NotifyConfig           `yaml:"notify"`
+	Report      ReportConfig           `yaml:"report"`
+	// TLS holds certificate settings by API host, with "*" for any other
+	// host.
+	TLS map[string]TLSHostConfig `yaml:"tls"`
+	// FetchMaxAge is how old the last fetch may be before commands warn
+	// that remote-tracking branches may be out of date. Empty turns the
+	// warning off.
+	FetchMaxAge string `yaml:"fetch_max_age"`
+	// LogFile is where git commands are logged when --log-file is not given.
+	LogFile string `yaml:"log_file"`
+	// AbsoluteDates shows dates as ISO dates rather than as ages such as
+	// "3 days ago".
+	AbsoluteDates bool `yaml:"absolute_dates"`
+	// Theme is the color theme used when --theme is not given: default or
+	// colorblind.
+	Theme string `yaml:"theme"`
+	// ListSuggestions shows the cleanup suggestions of suggest below the
+	// output of list.
+	ListSuggestions bool `yaml:"list_suggestions"`
+	// ConfigURL is an https URL of an organization-wide config file that
+	// the other settings are layered over. It is fetched at most once a
+	// day and cached. Only the global config and git config can set it.
+	ConfigURL string `yaml:"config_url"`
+}
+
+// FeatureConfig controls `feature start` and `feature finish`.
+type FeatureConfig struct {
+	Prefix string `yaml:"prefix"`
+	// Base is the branch features start from and finish into. Empty means
+	// the detected default branch.
+	Base string `yaml:"base"`
+	// Finish is how a feature is integrated: merge, rebase or none.
+	Finish       string `yaml:"finish"`
+	DeleteRemote bool   `yaml:"delete_remote"`
+	Prune        bool   `yaml:"prune"`
+}
+
+var loadedConfig *Config
+
+// stringList is a list of strings that may also be written as a single
+// string in the config file.
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = stringList{node.Value}
+		return nil
+	}
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*l = values
+	return nil
+}
+
+func defaultConfig() Config {
+	return Config{
+		Feature: FeatureConfig{
+			Prefix:       "feature/",
+			Finish:       "merge",
+			DeleteRemote: true,
+			Prune:        true,
+		},
+		Hosting: HostingConfig{
+			Open: "branch",
+		},
+		Policies: PolicyConfig{
+			CI: CIThresholds{MaxWarnings: -1},
+		},
+		FetchMaxAge: "7d",
+		Maintenance: MaintenanceConfig{
+			After:     "gc",
+			Threshold: 20,
+			PackRefs:  100,
+		},
+	}
+}
+
+// globalConfigPath returns the location of the user's config file.
+func globalConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, AppName, "config.yaml")
+}
+
+// repoConfigPath returns the location of the current repository's config
+// file, or "" outside a working tree.
+func repoConfigPath() string {
+	lines, err := gitLines("rev-parse", "--show-toplevel")
+	if err != nil || len(lines) == 0 {
+		return ""
+	}
+	return filepath.Join(lines[0], repoConfigFile)
+}
+
+// config returns the merged configuration, reading the config files and git
+// config on first use. Settings in the organization config from config_url
+// are overridden by global ones, those by the repository file, and git
+// config overrides all of them. Protected patterns from the organization
+// are always kept, and the repository file cannot make the settings of
+// keepUserOnlySettings.
+func config() *Config {
+	if loadedConfig != nil {
+		return loadedConfig
+	}
+
+	cfg := defaultConfig()
+	var orgProtected []string
+	if url := configURL(); url != "" {
+		data, err := orgConfig(url)
+		if err == nil {
+			err = yaml.Unmarshal(data, &cfg)
+		}
+		if err != nil {
+			warn("Ignoring organization config from %s: %s", describeConfigURL(url), err)
+			cfg = defaultConfig()
+		}
+		orgProtected = cfg.Protected
+	}
+	repoPath := repoConfigPath()
+	for _, path := range []string{globalConfigPath(), repoPath} {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		trusted := cfg
+		trusted.TLS = make(map[string]TLSHostConfig, len(cfg.TLS))
+		for host, settings := range cfg.TLS {
+			trusted.TLS[host] = settings
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			warn("Ignoring invalid config file %s: %s", path, err)
+		}
+		if path == repoPath {
+			keepUserOnlySettings(&cfg, trusted, path)
+		}
+	}
+	if err := applyGitConfig(&cfg); err != nil {
+		warn("Ignoring invalid %s.* git config: %s", AppName, err)
+	}
+	for _, pattern := range orgProtected {
+		if !contains(cfg.Protected, pattern) {
+			cfg.Protected = append(cfg.Protected, pattern)
+		}
+	}
+
+	loadedConfig = &cfg
+	return loadedConfig
+}
+
+// keepUserOnlySettings undoes the settings made by the repository's config
+// file at path that only the user's own config may make. The file is
+// committed, so anyone who can push to the repository could otherwise use
+// them to run commands on the machine of whoever cleans up a checkout, or to
+// have their API token or SMTP login sent to a server of their choosing.
+func keepUserOnlySettings(cfg *Config, trusted Config, path string) {
+	var ignored []string
+	if cfg.Notify != trusted.Notify {
+		cfg.Notify = trusted.Notify
+		ignored = append(ignored, "notify")
+	}
+	if cfg.ConfigURL != trusted.ConfigURL {
+		cfg.ConfigURL = trusted.ConfigURL
+		ignored = append(ignored, "config_url")
+	}
+	for _, setting := range []struct {
+		name           string
+		value, trusted *string
+	}{
+		{"hosting.provider", &cfg.Hosting.Provider, &trusted.Hosting.Provider},
+		{"hosting.token", &cfg.Hosting.Token, &trusted.Hosting.Token},
+		{"hosting.api_url", &cfg.Hosting.APIURL, &trusted.Hosting.APIURL},
+	} {
+		if *setting.value != *setting.trusted {
+			*setting.value = *setting.trusted
+			ignored = append(ignored, setting.name)
+		}
+	}
+	if cfg.Report.SMTP != trusted.Report.SMTP {
+		cfg.Report.SMTP = trusted.Report.SMTP
+		ignored = append(ignored, "report.smtp")
+	}
+	for host, settings := range cfg.TLS {
+		if settings.InsecureSkipVerify && !trusted.TLS[host].InsecureSkipVerify {
+			settings.InsecureSkipVerify = false
+			cfg.TLS[host] = settings
+			ignored = append(ignored, "tls."+host+".insecure_skip_verify")
+		}
+	}
+	if len(ignored) > 0 {
+		warn("Ignoring %s in %s; only your global config or git config can set it.", strings.Join(ignored, ", "), path)
+	}
+}
+
+// gitConfigLists are the settings that may be given several times in git
+// config. Protected patterns and identities are added to those from the
+// config files; bases replace them.
+var gitConfigLists = []string{"base", "protected", "identities"}
+
+// applyGitConfig layers the <app>.* git config keys from every scope over
+// cfg. Keys use the config file names with dashes for underscores, and
+// sections as git config subsections: gbm.base, gbm.log-file,
+// gbm.feature.delete-remote, gbm.filters.<name>, gbm.tls.<host>.ca-file.
+func applyGitConfig(cfg *Config) error {
+	var lines []string
+	var err error
+	if gitBinaryMissing() {
+		lines, err = goGitConfigLines(AppName)
+	} else {
+		lines, err = gitLines("config", "--get-regexp", `^`+AppName+`\.`)
+	}
+	if err != nil {
+		// git config exits with an error when no key matches.
+		return nil
+	}
+
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, line := range lines {
+		key, value, hasValue := strings.Cut(line, " ")
+		if !hasValue {
+			// A key without a value is a true boolean in git config.
+			value = "true"
+		}
+		setYAMLValue(root, gitConfigPath(strings.TrimPrefix(key, AppName+".")), value, gitConfigLists)
+	}
+
+	fromFiles := *cfg
+	cfg.Protected, cfg.Identities = nil, nil
+	if err := root.Decode(cfg); err != nil {
+		*cfg = fromFiles
+		return err
+	}
+	cfg.Protected = append(fromFiles.Protected, cfg.Protected...)
+	cfg.Identities = append(fromFiles.Identities, cfg.Identities...)
+	return nil
+}
+
+// gitConfigPath returns the config file path of a git config key with the
+// <app> section removed. As in git, the variable name follows the last dot
+// and the subsection, which may itself hold dots, comes before it; the first
+// part of the subsection names the setting and the rest is left as written,
+// as it names a host, filter or the like.
+func gitConfigPath(name string) []string {
+	dash := func(s string) string { return strings.ReplaceAll(s, "-", "_") }
+	last := strings.LastIndex(name, ".")
+	if last < 0 {
+		return []string{dash(name)}
+	}
+	subsection, variable := name[:last], dash(name[last+1:])
+	if setting, rest, ok := strings.Cut(subsection, "."); ok {
+		return []string{dash(setting), rest, variable}
+	}
+	return []string{dash(subsection), variable}
+}
+
+// setYAMLValue sets the value at path below the mapping node, creating
+// mappings as needed. Keys named in lists collect every value in a sequence.
+func setYAMLValue(node *yaml.Node, path []string, value string, lists []string) {
+	key := path[0]
+	var child *yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			child = node.Content[i+1]
+		}
+	}
+	if child == nil {
+		child = &yaml.Node{Kind: yaml.MappingNode}
+		if len(path) == 1 && contains(lists, key) {
+			child.Kind = yaml.SequenceNode
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, child)
+	}
+
+	scalar := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	switch {
+	case len(path) > 1:
+		if child.Kind != yaml.MappingNode {
+			*child = yaml.Node{Kind: yaml.MappingNode}
+		}
+		setYAMLValue(child, path[1:], value, nil)
+	case child.Kind == yaml.SequenceNode:
+		child.Content = append(child.Content, scalar)
+	default:
+		*child = *scalar
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestGitConfigPath(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"base", []string{"base"}},
+		{"log-file", []string{"log_file"}},
+		{"feature.delete-remote", []string{"feature", "delete_remote"}},
+		{"filters.stale", []string{"filters", "stale"}},
+		{"tls.git.example.com.ca-file", []string{"tls", "git.example.com", "ca_file"}},
+		{"tls.my-host.insecure-skip-verify", []string{"tls", "my-host", "insecure_skip_verify"}},
+	}
+	for _, tt := range tests {
+		if got := gitConfigPath(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("gitConfigPath(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetYAMLValue(t *testing.T) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, set := range []struct {
+		path  []string
+		value string
+	}{
+		{[]string{"base"}, "main"},
+		{[]string{"base"}, "develop"},
+		{[]string{"log_file"}, "a.log"},
+		{[]string{"log_file"}, "b.log"},
+		{[]string{"feature", "prefix"}, "feat/"},
+		{[]string{"feature", "delete_remote"}, "true"},
+		{[]string{"tls", "git.example.com", "ca_file"}, "/etc/ca.pem"},
+	} {
+		setYAMLValue(root, set.path, set.value, gitConfigLists)
+	}
+
+	var cfg Config
+	if err := root.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	// Lists collect every value; other keys keep the last one.
+	if want := (stringList{"main", "develop"}); !reflect.DeepEqual(cfg.Base, want) {
+		t.Errorf("base = %q, want %q", cfg.Base, want)
+	}
+	if cfg.LogFile != "b.log" {
+		t.Errorf("log_file = %q, want b.log", cfg.LogFile)
+	}
+	if cfg.Feature.Prefix != "feat/" || !cfg.Feature.DeleteRemote {
+		t.Errorf("feature = %+v", cfg.Feature)
+	}
+	if got := cfg.TLS["git.example.com"].CAFile; got != "/etc/ca.pem" {
+		t.Errorf("tls[git.example.com].ca_file = %q", got)
+	}
+}
+
+func TestApplyGitConfigDottedSubsection(t *testing.T) {
+	newTestRepo(t)
+	t.Setenv("GIT_CONFIG_COUNT", "2")
+	t.Setenv("GIT_CONFIG_KEY_0", "gbm.tls.git.example.com.ca-file")
+	t.Setenv("GIT_CONFIG_VALUE_0", "/etc/ca.pem")
+	t.Setenv("GIT_CONFIG_KEY_1", "gbm.feature.delete-remote")
+	t.Setenv("GIT_CONFIG_VALUE_1", "true")
+
+	var cfg Config
+	if err := applyGitConfig(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.TLS["git.example.com"].CAFile; got != "/etc/ca.pem" {
+		t.Errorf("tls[git.example.com].ca_file = %q, want /etc/ca.pem", got)
+	}
+	if !cfg.Feature.DeleteRemote {
+		t.Error("feature.delete_remote was not set")
+	}
+}
+
+func TestKeepUserOnlySettings(t *testing.T) {
+	trusted := defaultConfig()
+	trusted.Hosting.APIURL = "https://github.example.com/api/v3"
+	cfg := trusted
+	cfg.Hosting.APIURL = "https://attacker.example.com"
+	cfg.Hosting.Token = "repo-token"
+	cfg.Hosting.Provider = "github"
+	cfg.Hosting.Open = "pr"
+	cfg.Report.SMTP.Host = "smtp.attacker.example.com"
+	captureStdout(t, func() {
+		keepUserOnlySettings(&cfg, trusted, ".gbm.yaml")
+	})
+
+	if cfg.Hosting.APIURL != trusted.Hosting.APIURL || cfg.Hosting.Token != "" || cfg.Hosting.Provider != "" {
+		t.Errorf("hosting = %+v, want the user's settings", cfg.Hosting)
+	}
+	if cfg.Report.SMTP.Host != "" {
+		t.Errorf("report.smtp.host = %q, want it ignored", cfg.Report.SMTP.Host)
+	}
+	// Settings that send nothing anywhere are left to the repository.
+	if cfg.Hosting.Open != "pr" {
+		t.Errorf("hosting.open = %q, want pr", cfg.Hosting.Open)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// maxConflictFiles is how many conflicting files are named per branch.
+const maxConflictFiles = 3
+
+// mergeForecast is what merging a branch into the base would do.
+type mergeForecast struct {
+	merged    bool
+	conflicts []string
+}
+
+func runConflicts(args []string) {
+	sel, rest, err := parseSelection(args)
+	if err != nil {
+		fatal(err)
+	}
+	if len(rest) > 1 {
+		usageError("conflicts")
+	}
+	if len(rest) == 1 {
+		sel.pattern = rest[0]
+	}
+
+	branches, _, err := listBranches()
+	if err == nil {
+		branches, err = sel.filter(branches)
+	}
+	if err != nil {
+		warn("Error selecting branches: %s", err)
+		exit(1)
+	}
+	base := baseBranch()
+	branches = removeName(branches, base)
+	if len(branches) == 0 {
+		status("No branches to check.")
+		return
+	}
+	sortBranches(branches)
+
+	title("Forecast of merging into %s", base)
+	t := table{}
+	var clean, conflicting int
+	for i, branch := range branches {
+		forecast, err := forecastMerge(base, branch)
+		if err != nil {
+			warn("Error simulating the merge: %s", err)
+			exit(1)
+		}
+
+		result := "clean"
+		switch {
+		case forecast.merged:
+			result = "already merged"
+		case len(forecast.conflicts) > 0:
+			conflicting++
+			result = fmt.Sprintf("conflicts in %s", describeConflicts(forecast.conflicts))
+		default:
+			clean++
+		}
+		if plainOutput {
+			info("branch %d of %d: %s, %s", i+1, len(branches), branch, result)
+		} else {
+			t.addRow(branch, result)
+		}
+	}
+	for _, line := range t.lines() {
+		info("%s", line)
+	}
+	status("%s would merge cleanly, %s would conflict.", branchCount(clean), branchCount(conflicting))
+}
+
+// forecastMerge merges branch into base in memory with git merge-tree,
+// without touching the working tree or any ref.
+func forecastMerge(base, branch string) (mergeForecast, error) {
+	if gitQuiet("merge-base", "--is-ancestor", "refs/heads/"+branch, base) == nil {
+		return mergeForecast{merged: true}, nil
+	}
+
+	output, err := gitOutput("merge-tree", "--write-tree", "--name-only", "--no-messages", base, "refs/heads/"+branch)
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return mergeForecast{}, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		// The first line is the tree with conflict markers, then the
+		// conflicting files.
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		files := []string{}
+		for _, file := range lines[1:] {
+			if file != "" && !contains(files, file) {
+				files = append(files, file)
+			}
+		}
+		return mergeForecast{conflicts: files}, nil
+	}
+	return mergeForecast{}, fmt.Errorf("git merge-tree failed for %s; it needs git 2.38 or later: %w", branch, err)
+}
+
+// describeConflicts names the first few conflicting files.
+func describeConflicts(files []string) string {
+	if len(files) == 0 {
+		return "the merge"
+	}
+	if len(files) <= maxConflictFiles {
+		return strings.Join(files, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(files[:maxConflictFiles], ", "), len(files)-maxConflictFiles)
+}
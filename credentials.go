@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+)
+
+// credentialServices are the integrations whose secrets can be kept in the
+// OS keychain: the API token for a GitHub host and the password for the
+// report mail server.
+var credentialServices = []string{"github", "smtp"}
+
+// errNoCredential is returned when the keychain holds nothing for an
+// account.
+var errNoCredential = errors.New("no credential stored")
+
+// runAuth stores, shows or removes the secrets of the integrations in the OS
+// keychain, so that they need not be kept in config files or the
+// environment.
+func runAuth(args []string) {
+	if len(args) == 0 {
+		usageError("auth")
+	}
+	switch args[0] {
+	case "status":
+		if len(args) != 1 {
+			usageError("auth")
+		}
+		showAuthStatus()
+	case "login", "logout":
+		if len(args) < 2 || len(args) > 3 || !contains(credentialServices, args[1]) {
+			usageError("auth")
+		}
+		host := ""
+		if len(args) == 3 {
+			host = args[2]
+		}
+		account, err := credentialAccount(args[1], host)
+		if err != nil {
+			warn(err.Error())
+			exit(1)
+		}
+		if args[0] == "login" {
+			authLogin(account)
+		} else {
+			authLogout(account)
+		}
+	default:
+		usageError("auth")
+	}
+}
+
+// credentialAccount names the keychain entry of service on host, such as
+// github:github.com. The host defaults to that of the remote for github and
+// to report.smtp.host for smtp.
+func credentialAccount(service, host string) (string, error) {
+	if host == "" {
+		switch service {
+		case "github":
+			host = "github.com"
+			if loc, err := remoteLocation(remoteName); err == nil && config().Hosting.APIURL == "" {
+				host = loc.host
+			}
+		case "smtp":
+			host = config().Report.SMTP.Host
+			if host == "" {
+				return "", fmt.Errorf("report.smtp.host is not set; give the mail server's host")
+			}
+		}
+	}
+	return service + ":" + host, nil
+}
+
+func authLogin(account string) {
+	var secret string
+	var err error
+	if isTerminal(os.Stdin) {
+		secret, err = askSecret(fmt.Sprintf("Token or password for %s:", account))
+	} else {
+		// Lets the secret be piped in from a password manager.
+		secret, err = readLine(stdin)
+		stdinConsumed = true
+	}
+	if err != nil || secret == "" {
+		warn("No token given.")
+		exit(1)
+	}
+	if err := keychainSet(account, secret); err != nil {
+		warn("Error storing the token in the %s: %s", keychainName(), err)
+		exit(1)
+	}
+	status("Stored the token for %s in the %s.", account, keychainName())
+}
+
+func authLogout(account string) {
+	err := keychainDelete(account)
+	switch {
+	case errors.Is(err, errNoCredential):
+		status("No token is stored for %s.", account)
+	case err != nil:
+		warn("Error removing the token from the %s: %s", keychainName(), err)
+		exit(1)
+	default:
+		status("Removed the token for %s from the %s.", account, keychainName())
+	}
+}
+
+// showAuthStatus shows where the secret of each integration would be read
+// from.
+func showAuthStatus() {
+	title("Credentials")
+	for _, service := range credentialServices {
+		account, err := credentialAccount(service, "")
+		if err != nil {
+			info("%s: not configured", service)
+			continue
+		}
+		host := strings.TrimPrefix(account, service+":")
+		var source string
+		switch service {
+		case "github":
+			_, source = githubTokenSource(config().Hosting, host)
+		case "smtp":
+			_, source = smtpPassword(host)
+		}
+		if source == "" {
+			source = "none"
+		}
+		info("%s: %s", account, source)
+	}
+}
+
+// smtpPassword returns the password for the mail server on host and where
+// it came from: <APP>_SMTP_PASSWORD, or the keychain.
+func smtpPassword(host string) (password, source string) {
+	name := strings.ToUpper(AppName) + "_SMTP_PASSWORD"
+	if password := os.Getenv(name); password != "" {
+		return password, name
+	}
+	if password, err := keychainGet("smtp:" + host); err == nil {
+		return password, keychainName()
+	}
+	return "", ""
+}
+
+// keychainName is what the platform's secret store is called.
+func keychainName() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "macOS Keychain"
+	case "windows":
+		return "Windows Credential Manager"
+	}
+	return "Secret Service keyring"
+}
+
+// keychainGet reads the secret stored for account, using security on macOS,
+// PowerShell on Windows and secret-tool from libsecret elsewhere.
+func keychainGet(account string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", AppName, "-a", account, "-w")
+	case "windows":
+		cmd = windowsCredentialCommand("get", account)
+	default:
+		cmd = exec.Command("secret-tool", "lookup", "service", AppName, "account", account)
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		return "", keychainError(cmd, err)
+	}
+	secret := strings.TrimRight(string(output), "\r\n")
+	if secret == "" {
+		return "", errNoCredential
+	}
+	return secret, nil
+}
+
+// keychainSet stores secret for account, replacing any earlier one. The
+// secret is passed on stdin so that it does not show in the process list.
+func keychainSet(account, secret string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// security reads commands from stdin with -i.
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+			securityQuote(AppName), securityQuote(account), securityQuote(secret)))
+	case "windows":
+		cmd = windowsCredentialCommand("set", account)
+		cmd.Stdin = strings.NewReader(secret)
+	default:
+		cmd = exec.Command("secret-tool", "store", "--label="+AppName+" "+account, "service", AppName, "account", account)
+		cmd.Stdin = strings.NewReader(secret)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if message := strings.TrimSpace(string(output)); message != "" {
+			return errors.New(message)
+		}
+		return keychainError(cmd, err)
+	}
+	return nil
+}
+
+// keychainDelete removes the secret stored for account.
+func keychainDelete(account string) error {
+	if _, err := keychainGet(account); err != nil {
+		return err
+	}
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "delete-generic-password", "-s", AppName, "-a", account)
+	case "windows":
+		cmd = windowsCredentialCommand("delete", account)
+	default:
+		cmd = exec.Command("secret-tool", "clear", "service", AppName, "account", account)
+	}
+	if err := cmd.Run(); err != nil {
+		return keychainError(cmd, err)
+	}
+	return nil
+}
+
+// keychainError tells a missing keychain program apart from a lookup that
+// found nothing, which all of them report with a failing exit status.
+func keychainError(cmd *exec.Cmd, err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return errNoCredential
+	}
+	if runtime.GOOS != "darwin" && runtime.GOOS != "windows" {
+		return fmt.Errorf("%s not found; install libsecret-tools", cmd.Args[0])
+	}
+	return err
+}
+
+// securityQuote quotes an argument for a command line read by security -i.
+func securityQuote(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// windowsCredentialScript reads, writes or deletes a generic credential in
+// the Windows Credential Manager. The secret is written to stdout or read
+// from stdin.
+const windowsCredentialScript = `
+Add-Type -TypeDefinition @'
+using System;
+using System.Runtime.InteropServices;
+using System.Text;
+public static class GbmCredential {
+	[StructLayout(LayoutKind.Sequential, CharSet = CharSet.Unicode)]
+	struct CREDENTIAL {
+		public int Flags; public int Type; public string TargetName; public string Comment;
+		public System.Runtime.InteropServices.ComTypes.FILETIME LastWritten;
+		public int CredentialBlobSize; public IntPtr CredentialBlob; public int Persist;
+		public int AttributeCount; public IntPtr Attributes; public string TargetAlias; public string UserName;
+	}
+	[DllImport("advapi32.dll", CharSet = CharSet.Unicode, SetLastError = true)]
+	static extern bool CredReadW(string target, int type, int flags, out IntPtr credential);
+	[DllImport("advapi32.dll", CharSet = CharSet.Unicode, SetLastError = true)]
+	static extern bool CredWriteW(ref CREDENTIAL credential, int flags);
+	[DllImport("advapi32.dll", CharSet = CharSet.Unicode, SetLastError = true)]
+	static extern bool CredDeleteW(string target, int type, int flags);
+	[DllImport("advapi32.dll")]
+	static extern void CredFree(IntPtr credential);
+	public static string Read(string target) {
+		IntPtr p;
+		if (!CredReadW(target, 1, 0, out p)) return null;
+		CREDENTIAL c = (CREDENTIAL)Marshal.PtrToStructure(p, typeof(CREDENTIAL));
+		byte[] blob = new byte[c.CredentialBlobSize];
+		Marshal.Copy(c.CredentialBlob, blob, 0, blob.Length);
+		CredFree(p);
+		return Encoding.Unicode.GetString(blob);
+	}
+	public static bool Write(string target, string secret) {
+		byte[] blob = Encoding.Unicode.GetBytes(secret);
+		CREDENTIAL c = new CREDENTIAL();
+		c.Type = 1; c.TargetName = target; c.UserName = target; c.Persist = 2;
+		c.CredentialBlobSize = blob.Length;
+		c.CredentialBlob = Marshal.AllocHGlobal(blob.Length);
+		Marshal.Copy(blob, 0, c.CredentialBlob, blob.Length);
+		try { return CredWriteW(ref c, 0); } finally { Marshal.FreeHGlobal(c.CredentialBlob); }
+	}
+	public static bool Delete(string target) { return CredDeleteW(target, 1, 0); }
+}
+'@
+$target = $env:CREDENTIAL_TARGET
+switch ($env:CREDENTIAL_OP) {
+	'get' { $s = [GbmCredential]::Read($target); if ($s -eq $null) { exit 1 }; [Console]::Out.Write($s) }
+	'set' { if (-not [GbmCredential]::Write($target, [Console]::In.ReadToEnd())) { exit 1 } }
+	'delete' { if (-not [GbmCredential]::Delete($target)) { exit 1 } }
+}
+`
+
+// windowsCredentialCommand runs windowsCredentialScript for op on the
+// credential of account. The script is passed encoded, as PowerShell
+// expects, so that stdin stays free for the secret.
+func windowsCredentialCommand(op, account string) *exec.Cmd {
+	units := utf16.Encode([]rune(windowsCredentialScript))
+	encoded := make([]byte, 0, 2*len(units))
+	for _, u := range units {
+		encoded = append(encoded, byte(u), byte(u>>8))
+	}
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-EncodedCommand", base64.StdEncoding.EncodeToString(encoded))
+	cmd.Env = append(os.Environ(), "CREDENTIAL_OP="+op, "CREDENTIAL_TARGET="+AppName+":"+account)
+	return cmd
+}
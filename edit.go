@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+)
+
+const editHelp = `
+# Change the first column of each branch to choose what happens to it:
+#   k, keep    = leave the branch alone
+#   d, delete  = delete the branch
+#   a, archive = tag the tip as ` + archiveTagPrefix + `<name>, then delete the branch
+#
+# Removing a line keeps the branch. Save an unchanged file to do nothing.
+`
+
+func runEdit(args []string) {
+	opts, rest := parseDeleteOptions(args, false)
+	sel, rest, err := parseSelection(rest)
+	if err != nil {
+		fatal(err)
+	}
+	if len(rest) > 1 {
+		usageError("edit")
+	}
+	if len(rest) == 1 {
+		sel.pattern = rest[0]
+	}
+
+	branches, currentBranch, err := listBranches()
+	if err == nil {
+		branches, err = sel.filter(branches)
+	}
+	if err != nil {
+		warn("Error selecting branches: %s", err)
+		exit(1)
+	}
+	if len(branches) == 0 {
+		status("No branches match the given pattern and filters.")
+		return
+	}
+	sortBranches(branches)
+
+	path, err := writeEditFile(branches)
+	if err != nil {
+		warn("Error writing branch list: %s", err)
+		exit(1)
+	}
+	defer os.Remove(path)
+
+	if err := runEditor(path); err != nil {
+		warn("Editor failed, nothing was changed: %s", err)
+		exit(1)
+	}
+
+	toDelete, toArchive, err := readEditFile(path, branches)
+	if err != nil {
+		warn("%s. Nothing was changed.", err)
+		exit(1)
+	}
+	if len(toDelete) == 0 && len(toArchive) == 0 {
+		status("Nothing to do.")
+		return
+	}
+
+	if len(toArchive) > 0 {
+		archiveBranches(toArchive, currentBranch)
+	}
+	if len(toDelete) > 0 {
+		confirmAndDeleteBranches(toDelete, currentBranch, opts)
+	}
+}
+
+// writeEditFile writes branches to a temporary file, one per line with a
+// keep marker and a summary of the branch, followed by instructions.
+func writeEditFile(branches []string) (string, error) {
+	infos, err := loadBranchInfos(branches)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp("", AppName+"-edit-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	width := 0
+	for _, branch := range branches {
+		width = max(width, displayWidth(branch))
+	}
+	w := bufio.NewWriter(f)
+	for _, branch := range branches {
+		b := infos[branch]
+		fmt.Fprintf(w, "k %s  # %s, %s by %s, %s\n", padRight(branch, width), shortSHA(b.SHA), formatWhen(b.Date), b.Author, b.mergedLabel())
+	}
+	fmt.Fprint(w, editHelp)
+	if err := w.Flush(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// runEditor opens path in the editor git would use.
+func runEditor(path string) error {
+	lines, err := gitLines("var", "GIT_EDITOR")
+	if err != nil || len(lines) == 0 {
+		return fmt.Errorf("no editor configured; set $EDITOR")
+	}
+	// The editor setting may include arguments, so let the shell split it
+	// as git does.
+	cmd := exec.Command("sh", "-c", lines[0]+` "$@"`, lines[0], path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if !isTerminal(os.Stdin) && openTTY() {
+		cmd.Stdin, cmd.Stdout = tty, tty
+	}
+	return cmd.Run()
+}
+
+// readEditFile reads the edited branch list back, returning the branches
+// marked for deletion and for archiving. Only branches that were offered
+// may appear.
+func readEditFile(path string, offered []string) (toDelete, toArchive []string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := make(map[string]bool)
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(stripComment(line))
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, nil, fmt.Errorf("line %d: expected a marker and a branch name", i+1)
+		}
+		marker, branch := fields[0], fields[1]
+		if !contains(offered, branch) {
+			return nil, nil, fmt.Errorf("line %d: %s was not in the list", i+1, branch)
+		}
+		if seen[branch] {
+			return nil, nil, fmt.Errorf("line %d: %s is listed twice", i+1, branch)
+		}
+		seen[branch] = true
+
+		switch marker {
+		case "k", "keep":
+		case "d", "delete":
+			toDelete = append(toDelete, branch)
+		case "a", "archive":
+			toArchive = append(toArchive, branch)
+		default:
+			return nil, nil, fmt.Errorf("line %d: unknown marker %q; use k, d or a", i+1, marker)
+		}
+	}
+	return toDelete, toArchive, nil
+}
+
+// stripComment removes a # comment from line. As # may appear in branch
+// names such as fix#12, it only starts a comment at the start of the line or
+// after whitespace.
+func stripComment(line string) string {
+	for i, r := range line {
+		if r == '#' && (i == 0 || unicode.IsSpace(rune(line[i-1]))) {
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadEditFile(t *testing.T) {
+	offered := []string{"feature/a", "fix#12", "old", "spike"}
+	tests := []struct {
+		name      string
+		content   string
+		toDelete  []string
+		toArchive []string
+		err       string
+	}{
+		{
+			name: "markers and comments",
+			content: "# Change the first column\n" +
+				"k feature/a  # 1a2b3c4, 3 days ago by Ann, merged\n" +
+				"delete fix#12  # 5d6e7f8\n" +
+				"  a old\n" +
+				"\n" +
+				"#d spike\n",
+			toDelete:  []string{"fix#12"},
+			toArchive: []string{"old"},
+		},
+		{
+			name:     "removed lines keep their branch",
+			content:  "d spike\n",
+			toDelete: []string{"spike"},
+		},
+		{name: "unknown marker", content: "x old\n", err: "line 1: unknown marker"},
+		{name: "not offered", content: "d main\n", err: "main was not in the list"},
+		{name: "listed twice", content: "d old\nk old\n", err: "line 2: old is listed twice"},
+		{name: "missing marker", content: "\nold\n", err: "line 2: expected a marker"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "branches")
+		if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		toDelete, toArchive, err := readEditFile(path, offered)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(toDelete, tt.toDelete) || !reflect.DeepEqual(toArchive, tt.toArchive) {
+			t.Errorf("%s: got delete %q, archive %q; want %q, %q", tt.name, toDelete, toArchive, tt.toDelete, tt.toArchive)
+		}
+	}
+}
+
+func TestStripComment(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"d fix#12", "d fix#12"},
+		{"d fix#12 # note", "d fix#12 "},
+		{"d fix#12\t#note", "d fix#12\t"},
+		{"# comment", ""},
+		{"k main", "k main"},
+	}
+	for _, tt := range tests {
+		if got := stripComment(tt.in); got != tt.want {
+			t.Errorf("stripComment(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
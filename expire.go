@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultRetention is how long reflog entries of deleted work are kept by
+// `expire` unless --older-than says otherwise.
+const defaultRetention = "30d"
+
+func runExpire(args []string) {
+	olderThan, _, args := extractOption(args, "--older-than")
+	if len(args) != 0 {
+		usageError("expire")
+	}
+	if olderThan == "" {
+		olderThan = defaultRetention
+	}
+	age, err := parseAge(olderThan)
+	if err != nil {
+		fatal(err)
+	}
+	cutoff := time.Now().Add(-age)
+
+	trashed, err := expiredTrash(cutoff)
+	if err != nil {
+		warn("Error reading the trash: %s", err)
+		exit(1)
+	}
+	count, err := expirableReflogEntries(cutoff.Format("2006-01-02 15:04:05 -0700"))
+	if err != nil {
+		warn("Error reading reflogs: %s", err)
+		exit(1)
+	}
+	if count == 0 && len(trashed) == 0 {
+		status("No reflog entries or trashed branches of deleted work are older than %s.", olderThan)
+		return
+	}
+
+	if len(trashed) > 0 {
+		title("%s deleted more than %s ago can still be restored from the trash.", branchCount(len(trashed)), olderThan)
+	}
+	if count > 0 {
+		title("%d reflog entries older than %s point to commits that are no longer on any branch.", count, olderThan)
+	}
+	info("Removing them lets git gc delete those commits, so deleted branches can no longer be restored or recovered from the reflog.")
+	if dryRun {
+		status("Dry run, nothing was removed.")
+		return
+	}
+	if !confirmDeletion() {
+		return
+	}
+
+	if len(trashed) > 0 {
+		var commands strings.Builder
+		for _, t := range trashed {
+			fmt.Fprintf(&commands, "delete %s\n", t.ref())
+		}
+		if output, err := gitWithInput(commands.String(), "update-ref", "--stdin"); err != nil {
+			warn("Error emptying the trash: %s", strings.TrimSpace(string(output)))
+			exit(1)
+		}
+	}
+	// Dropping the trash refs can leave more entries unreachable, so the
+	// reflogs are expired even when none were counted before.
+	if err := gitRun("reflog", "expire", "--expire-unreachable="+cutoff.Format("2006-01-02 15:04:05 -0700"), "--all"); err != nil {
+		warn("Error expiring reflog entries.")
+		exit(1)
+	}
+	status("Trash and reflog entries removed. The commits are deleted at the next git gc.")
+}
+
+// expirableReflogEntries counts the reflog entries older than cutoff whose
+// commits are not reachable from any ref.
+func expirableReflogEntries(cutoff string) (int, error) {
+	output, err := gitCombinedOutput("reflog", "expire", "--dry-run", "--verbose", "--expire-unreachable="+cutoff, "--all")
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, "would prune ") {
+			count++
+		}
+	}
+	return count, nil
+}
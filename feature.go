@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+)
+
+func runFeature(args []string) {
+	finish := config().Feature.Finish
+	for _, mode := range []string{"merge", "rebase", "none"} {
+		var set bool
+		if set, args = extractFlag(args, "--"+mode); set {
+			finish = mode
+		}
+	}
+	keepRemote, args := extractFlag(args, "--keep-remote")
+
+	if len(args) != 2 || (args[0] != "start" && args[0] != "finish") {
+		usageError("feature")
+	}
+
+	base := config().Feature.Base
+	if base == "" || len(baseOverrides) > 0 {
+		base = baseBranch()
+	}
+
+	branch := featureBranchName(args[1])
+	if args[0] == "start" {
+		startFeature(branch, base)
+	} else {
+		finishFeature(branch, base, finish, config().Feature.DeleteRemote && !keepRemote)
+	}
+}
+
+// featureBranchName adds the configured feature prefix to name unless it is
+// already there.
+func featureBranchName(name string) string {
+	prefix := config().Feature.Prefix
+	if strings.HasPrefix(name, prefix) {
+		return name
+	}
+	return prefix + name
+}
+
+func startFeature(branch, base string) {
+	title("Starting %s from %s", branch, base)
+	if err := gitRun("switch", "-c", branch, base); err != nil {
+		exit(1)
+	}
+	status("Feature branch %s is ready.", branch)
+}
+
+// finishFeature integrates branch into base according to mode, then deletes
+// it locally and, if requested, on the remote.
+func finishFeature(branch, base, mode string, deleteRemote bool) {
+	if !branchExists(branch) {
+		warn("No such branch %s.", branch)
+		exit(1)
+	}
+
+	title("Finishing %s into %s (%s)", branch, base, mode)
+
+	var steps [][]string
+	switch mode {
+	case "merge":
+		steps = [][]string{
+			{"switch", base},
+			{"merge", "--no-ff", "--no-edit", branch},
+		}
+	case "rebase":
+		steps = [][]string{
+			{"rebase", base, branch},
+			{"switch", base},
+			{"merge", "--ff-only", branch},
+		}
+	case "none":
+		steps = [][]string{{"switch", base}}
+	default:
+		fatalf("Unknown finish mode %q. Use merge, rebase or none.", mode)
+	}
+
+	if dryRun {
+		for _, step := range steps {
+			info("Would run 'git %s'", strings.Join(step, " "))
+		}
+		info("Would delete %s", branch)
+		if deleteRemote && remoteExists(remoteName) {
+			info("Would delete %s from %s", branch, remoteName)
+		}
+		status("Dry run, %s was not finished.", branch)
+		return
+	}
+	for _, step := range steps {
+		if err := gitRun(step...); err != nil {
+			warn("Stopped at 'git %s'. Resolve the problem and run the command again.", strings.Join(step, " "))
+			exit(1)
+		}
+	}
+
+	if err := deleteBranch(branch, false); err != nil {
+		warn(err.Error())
+		exit(1)
+	}
+
+	if deleteRemote && remoteExists(remoteName) {
+		if _, err := gitLines("show-ref", "--verify", "refs/remotes/"+remoteName+"/"+branch); err == nil {
+			if err := gitRun("push", remoteName, "--delete", branch); err != nil {
+				warn("Error deleting %s from %s.", branch, remoteName)
+			}
+		}
+	}
+
+	if config().Feature.Prune && remoteExists(remoteName) {
+		if err := gitRun("remote", "prune", remoteName); err != nil {
+			warn("Error pruning %s.", remoteName)
+		}
+	}
+
+	status("Feature %s finished.", branch)
+}
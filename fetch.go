@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// staleFetchWarned is set once the stale fetch warning has been shown, so a
+// command shows it at most once.
+var staleFetchWarned bool
+
+// lastFetch returns when the repository was last fetched, or false if that
+// is unknown, as in a fresh clone.
+func lastFetch() (time.Time, bool) {
+	common, err := gitCommonDir()
+	if err != nil {
+		return time.Time{}, false
+	}
+	stat, err := os.Stat(filepath.Join(common, "FETCH_HEAD"))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return stat.ModTime(), true
+}
+
+// warnIfFetchIsStale warns when remote-tracking branches are older than the
+// fetch_max_age setting, since merged and gone checks rely on them.
+func warnIfFetchIsStale() {
+	if staleFetchWarned || config().FetchMaxAge == "" || !remoteExists(remoteName) {
+		return
+	}
+	staleFetchWarned = true
+
+	limit, err := parseAge(config().FetchMaxAge)
+	if err != nil {
+		warn("Ignoring invalid fetch_max_age: %s", err)
+		return
+	}
+	fetched, ok := lastFetch()
+	if !ok || time.Since(fetched) < limit {
+		return
+	}
+	warn("Remote-tracking branches were last fetched %s, so merged and gone results may be out of date. Use --fetch to update them first.", formatWhen(fetched))
+}
+
+// fetchRemote updates the remote-tracking branches of the current remote,
+// dropping those deleted on the remote.
+func fetchRemote() {
+	if !remoteExists(remoteName) {
+		return
+	}
+	title("Fetching %s", remoteName)
+	if err := gitRun("fetch", "--prune", remoteName); err != nil {
+		warn("Error fetching %s.", remoteName)
+		exit(1)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ghCLIToken returns the token the gh CLI is logged in to host with, so
+// users of gh need no further setup. It asks gh itself, which knows about
+// tokens kept in the system keyring, and falls back to gh's hosts.yml for
+// versions without `gh auth token`.
+func ghCLIToken(host string) string {
+	if token, ok := ghTokens[host]; ok {
+		return token
+	}
+	token := askGHForToken(host)
+	if token == "" {
+		token = ghHostsToken(host)
+	}
+	ghTokens[host] = token
+	return token
+}
+
+// ghTokens caches the tokens found by ghCLIToken, by host.
+var ghTokens = make(map[string]string)
+
+func askGHForToken(host string) string {
+	if path, err := exec.LookPath("gh"); err == nil {
+		output, err := exec.Command(path, "auth", "token", "--hostname", host).Output()
+		if token := strings.TrimSpace(string(output)); err == nil && token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+// ghHostsToken reads the token for host from gh's hosts.yml.
+func ghHostsToken(host string) string {
+	dir := os.Getenv("GH_CONFIG_DIR")
+	if dir == "" {
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+			dir = filepath.Join(xdg, "gh")
+		} else if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".config", "gh")
+		} else {
+			return ""
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "hosts.yml"))
+	if err != nil {
+		return ""
+	}
+	var hosts map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+	}
+	if err := yaml.Unmarshal(data, &hosts); err != nil {
+		return ""
+	}
+	return hosts[host].OAuthToken
+}
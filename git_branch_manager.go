@@ -1,148 +1,628 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 )
 
 const (
 	AppName = "gbm"
+
+	// staleAfter is how long a branch may go without commits before
+	// `list --stale` reports it.
+	staleAfter = 90 * 24 * time.Hour
 )
 
 var (
-	title     func(string, ...interface{})
-	info      func(string, ...interface{})
-	warn      func(string, ...interface{})
-	status    func(string, ...interface{})
-	lastColor color.Attribute
+	title  func(string, ...interface{})
+	info   func(string, ...interface{})
+	warn   func(string, ...interface{})
+	status func(string, ...interface{})
+
+	plainOutput bool
+
+	// remoteName is the remote that remote-aware operations act on.
+	remoteName = "origin"
+
+	// baseOverrides are the base branches given with --base, if any.
+	baseOverrides []string
+
+	// dryRun is set by --dry-run. Commands show what they would delete or
+	// rewrite and stop where they would ask for confirmation, and the git
+	// helpers refuse any command that would change the repository.
+	dryRun bool
 )
 
 func init() {
-	cyan := color.New(color.FgCyan).PrintfFunc()
-	hiCyan := color.New(color.FgHiCyan).PrintfFunc()
-	t := color.New(color.FgGreen, color.Bold).PrintfFunc()
+	setTheme("default")
+}
+
+// setPlainOutput replaces the colored printers with uncolored ones that do not
+// alternate shades, for screen readers and dumb terminals.
+func setPlainOutput() {
+	plainOutput = true
+	color.NoColor = true
+
 	title = func(format string, a ...interface{}) {
-		t("\n"+format+"\n", a...)
+		fmt.Fprintf(color.Output, "\n"+format+"\n", a...)
 	}
-
-	s := color.New(color.FgBlue, color.Bold).PrintfFunc()
 	status = func(format string, a ...interface{}) {
-		s("\n"+format+"\n\n", a...)
+		fmt.Fprintf(color.Output, "\n"+format+"\n\n", a...)
 	}
-
 	info = func(format string, a ...interface{}) {
-		if lastColor == color.FgCyan {
-			hiCyan(format+"\n", a...)
-			lastColor = color.FgHiCyan
+		fmt.Fprintf(color.Output, format+"\n", a...)
+	}
+	warn = func(format string, a ...interface{}) {
+		fmt.Fprintf(color.Output, format+"\n", a...)
+	}
+}
+
+// extractFlag reports whether any of names appears in args and returns args
+// with every occurrence removed.
+func extractFlag(args []string, names ...string) (bool, []string) {
+	found := false
+	var rest []string
+	for _, arg := range args {
+		if contains(names, arg) {
+			found = true
 		} else {
-			cyan(format+"\n", a...)
-			lastColor = color.FgCyan
+			rest = append(rest, arg)
 		}
 	}
+	return found, rest
+}
 
-	w := color.New(color.FgYellow, color.Bold).PrintfFunc()
-	warn = func(format string, a ...interface{}) {
-		w(format+"\n", a...)
+// extractOption finds the value of the option name, given as either
+// "name value" or "name=value", and returns args without it.
+func extractOption(args []string, name string) (string, bool, []string) {
+	values, rest := extractOptions(args, name)
+	if len(values) == 0 {
+		return "", false, rest
 	}
+	return values[len(values)-1], true, rest
+}
+
+// extractOptions is extractOption for options that may be repeated, returning
+// every value in order.
+func extractOptions(args []string, name string) ([]string, []string) {
+	var values []string
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == name:
+			if i+1 >= len(args) {
+				fatalf("Option %s requires a value", name)
+			}
+			values = append(values, args[i+1])
+			i++
+		case strings.HasPrefix(arg, name+"="):
+			values = append(values, strings.TrimPrefix(arg, name+"="))
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return values, rest
+}
+
+// splitAtDoubleDash splits args at the first "--", returning the arguments
+// before it and "--" with everything after it.
+func splitAtDoubleDash(args []string) ([]string, []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], args[i:]
+		}
+	}
+	return args, nil
 }
 
 func main() {
-	args := os.Args[1:]
+	// Global options are only read before "--", so the command line that
+	// foreach-submodule passes on keeps its own options.
+	args, passedOn := splitAtDoubleDash(os.Args[1:])
+	plain, args := extractFlag(args, "--plain")
+	themeName, _, args := extractOption(args, "--theme")
+	if themeName == "" {
+		themeName = config().Theme
+	}
+	absolute, args := extractFlag(args, "--absolute-dates")
+	absoluteDates = absolute || config().AbsoluteDates
+	if plain || os.Getenv("TERM") == "dumb" {
+		setPlainOutput()
+	} else if themeName != "" {
+		if err := setTheme(themeName); err != nil {
+			fatalf("Cannot set theme: %s", err)
+		}
+	}
+
+	logFile, _, args := extractOption(args, "--log-file")
+	if logFile == "" {
+		logFile = config().LogFile
+	}
+	if logFile != "" {
+		if err := openGitLog(logFile); err != nil {
+			fatalf("Cannot open log file: %s", err)
+		}
+	}
+
+	noGit, args := extractFlag(args, "--no-git-binary")
+	noGitBinary = noGit || gitBinaryMissing()
+
+	if remote, ok, rest := extractOption(args, "--remote"); ok {
+		if !remoteExists(remote) {
+			fatalf("Unknown remote %s", remote)
+		}
+		remoteName, args = remote, rest
+	}
+	baseOverrides, args = extractOptions(args, "--base")
+	dryRun, args = extractFlag(args, "--dry-run")
+	fetch, args := extractFlag(args, "--fetch")
+	deepen, _, args := extractOption(args, "--deepen")
+	args = append(args, passedOn...)
 
 	if len(args) == 0 {
-		log.Fatalf("Usage: %s [list|keep|Keep|delete|Delete]", AppName)
+		showUsage(os.Stderr)
+		exit(1)
+	}
+
+	c, ok := findCommand(args[0])
+	if !ok {
+		fatalf("Invalid command. Use %s.", quotedCommandList())
+	}
+	if !c.noRepo && !wantsHelp(args[1:]) {
+		checkRepository(c)
+	}
+	if c.locked {
+		lockRepository(args)
+		defer unlockRepository()
+	}
+	if fetch {
+		fetchRemote()
+	}
+	if deepen != "" {
+		deepenHistory(deepen)
+	}
+	runCommand(c, args[1:])
+}
+
+func runList(args []string) {
+	sel, rest, err := parseSelection(args)
+	if err != nil {
+		fatal(err)
+	}
+	opts := listOptions{selection: sel}
+	opts.count, rest = extractFlag(rest, "--count")
+	byAuthor, rest := extractFlag(rest, "--by-author")
+	groupBy, grouped, rest := extractOption(rest, "--group-by")
+	switch {
+	case grouped && groupBy != "author" && groupBy != "label":
+		fatalf("Unknown --group-by %q. Use author or label.", groupBy)
+	case grouped:
+		opts.groupBy = groupBy
+	case byAuthor:
+		opts.groupBy = "author"
 	}
+	opts.long, rest = extractFlag(rest, "--long")
+	opts.vv, rest = extractFlag(rest, "--vv")
+	opts.page, opts.perPage, rest = parsePageOptions(rest)
+	remotes, rest := extractFlag(rest, "--remotes")
+	asJSON, rest := extractFlag(rest, "--json")
+	if len(rest) > 1 {
+		usageError("list")
+	}
+	if len(rest) == 1 {
+		opts.pattern = rest[0]
+	}
+	if asJSON {
+		listBranchesAsJSON(opts.selection)
+		return
+	}
+	if remotes {
+		listRemoteBranches(opts.selection)
+		return
+	}
+	listSortedBranches(opts)
+	if !opts.count {
+		showListSuggestions()
+	}
+}
 
-	switch args[0] {
-	case "list":
-		listSortedBranches()
-	case "keep", "Keep":
-		if len(args) < 2 {
-			log.Fatalf("Usage: %s keep|Keep [branches to keep...]", AppName)
+// runKeep deletes every selected branch except those named in args; force
+// deletes unmerged branches too.
+func runKeep(args []string, force bool) {
+	opts, rest := parseDeleteOptions(args, force)
+	sel, rest, err := parseSelection(rest)
+	if err != nil {
+		fatal(err)
+	}
+	last, byCheckout, rest := parseKeepLast(rest)
+	fromFile, useFile, rest := extractOption(rest, "--from-file")
+	if useFile {
+		names, err := readBranchFile(fromFile)
+		if err != nil {
+			fatal("Error reading branch file:", err)
 		}
-		force := args[0] == "Keep"
-		keepBranches(args[1:], force)
-	case "delete", "Delete":
-		if len(args) < 2 {
-			log.Fatalf("Usage: %s delete|Delete [pattern]", AppName)
+		rest = append(rest, names...)
+	}
+	if len(rest) == 0 && last < 0 {
+		usageError("keep")
+	}
+	if last >= 0 {
+		rest = append(rest, newestBranches(last, byCheckout)...)
+	}
+	keepBranches(rest, sel, opts)
+}
+
+// runDelete deletes the branches matching a pattern or filters, or named on
+// stdin or in a file; force deletes unmerged branches too.
+func runDelete(args []string, force bool) {
+	opts, rest := parseDeleteOptions(args, force)
+	sel, rest, err := parseSelection(rest)
+	if err != nil {
+		fatal(err)
+	}
+	fromStdin, rest := extractFlag(rest, "--stdin")
+	fromFile, useFile, rest := extractOption(rest, "--from-file")
+	// The branches come from one place: a file, stdin or a pattern.
+	if (useFile && (fromStdin || len(rest) > 0)) || (fromStdin && len(rest) > 0) {
+		usageError("delete")
+	}
+	if useFile {
+		names, err := readBranchFile(fromFile)
+		if err != nil {
+			fatal("Error reading branch file:", err)
 		}
-		force := args[0] == "Delete"
-		deleteBranchesByPattern(args[1], force)
-	default:
-		log.Fatalf("Invalid command. Use 'list', 'keep', 'Keep', 'delete' or 'Delete'.")
+		deleteNamedBranches(names, sel, opts)
+		return
+	}
+	if fromStdin || (len(rest) == 1 && rest[0] == "-") {
+		names, err := readBranchNames(stdin)
+		stdinConsumed = true
+		if err != nil {
+			fatal("Error reading branch names from stdin:", err)
+		}
+		deleteNamedBranches(names, sel, opts)
+		return
+	}
+	if len(rest) == 1 {
+		sel.pattern = rest[0]
+	}
+	if len(rest) > 1 || (sel.pattern == "" && !sel.hasFilters()) {
+		usageError("delete")
+	}
+	deleteSelectedBranches(sel, opts)
+}
+
+// showDryRunDeletion lists the branches a dry run leaves alone where they
+// would otherwise be deleted without asking, as when resuming.
+func showDryRunDeletion(branches []string) {
+	for _, branch := range branches {
+		info("Would delete branch %s", branch)
 	}
+	status("Dry run, nothing was deleted.")
 }
 
 func confirmDeletion() bool {
+	return askDeletion(false) == "yes"
+}
+
+// askDeletion asks whether to go ahead with a deletion and returns "yes",
+// "no" or, if allowEdit is set, "edit".
+func askDeletion(allowEdit bool) string {
+	prompt := "\nType 'yes' to confirm deletion or 'no' to cancel:\n"
+	if allowEdit {
+		prompt = "\nType 'yes' to confirm deletion, 'edit' to remove branches from it, or 'no' to cancel:\n"
+	}
+	if dryRun {
+		status("Dry run, nothing was deleted.")
+		return "no"
+	}
 	for {
-		warn("\nType 'yes' to confirm deletion or 'no' to cancel:\n")
-		var input string
-		fmt.Scanln(&input)
-		fmt.Println() // Print a newline
+		input, err := ask(prompt)
+		fmt.Fprintln(color.Output) // Print a newline
+		if err != nil {
+			status("No confirmation received, deletion cancelled")
+			return "no"
+		}
 		if input == "yes" {
-			return true
+			return input
 		} else if input == "no" {
 			status("Deletion cancelled")
-			return false
+			return input
+		} else if input == "edit" && allowEdit {
+			return input
 		}
 	}
 }
 
 func _deleteBranches(branches []string, force bool) map[string]string {
 	failed := make(map[string]string)
+	run, err := trashBranches(branches)
+	if err != nil {
+		warn("Cannot keep the branches for undo: %s", err)
+	}
 	branchCount := len(branches)
 	if branchCount == 1 {
 		title("Deleting branch %s...", branches[0])
 	} else {
 		title("Deleting %d branches...", branchCount)
 	}
+	processWithCheckpoint(checkpoint{Operation: "delete", Force: force}, chunkArgs(branches, maxArgBytes), func(chunk []string) {
+		for branch, errMsg := range deleteBranchBatch(chunk, force) {
+			failed[branch] = errMsg
+			deleteErrors[branch] = errMsg
+		}
+	})
+	if run != "" && len(failed) > 0 {
+		var kept []string
+		for branch := range failed {
+			kept = append(kept, branch)
+		}
+		untrashBranches(run, kept)
+	}
+	return failed
+}
+
+// maxArgBytes bounds the branch names passed to one git invocation, keeping
+// well inside the command-line limit of every platform.
+const maxArgBytes = 30000
+
+// chunkArgs splits args into groups whose combined length stays under limit.
+func chunkArgs(args []string, limit int) [][]string {
+	var chunks [][]string
+	var chunk []string
+	size := 0
+	for _, arg := range args {
+		if len(chunk) > 0 && size+len(arg)+1 > limit {
+			chunks = append(chunks, chunk)
+			chunk, size = nil, 0
+		}
+		chunk = append(chunk, arg)
+		size += len(arg) + 1
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// erroredBranch returns which of branches a git branch error line, such as
+// "error: branch 'x' not found.", is about, or "" for none. Git quotes the
+// branch first in the line, and as names may contain quotes themselves, the
+// name must be followed by its closing quote and then a space, a full stop
+// or the end of the line.
+func erroredBranch(line string, branches []string) string {
+	_, quoted, ok := strings.Cut(line, "'")
+	if !ok {
+		return ""
+	}
+	found := ""
 	for _, branch := range branches {
-		err := deleteBranch(branch, force)
-		if err != nil {
-			failed[branch] = err.Error()
+		after, ok := strings.CutPrefix(quoted, branch+"'")
+		if ok && (after == "" || after[0] == ' ' || after[0] == '.') && len(branch) > len(found) {
+			found = branch
+		}
+	}
+	return found
+}
+
+// deleteBranchBatch deletes branches with one git branch call and returns the
+// error reported for each branch that was not deleted. git keeps going after
+// a failure, so the outcome of each branch is read from its output.
+func deleteBranchBatch(branches []string, force bool) map[string]string {
+	flag := "-d"
+	if force {
+		flag = "-D"
+	}
+	args := append([]string{"branch", flag, "--"}, branches...)
+	if refusedInDryRun(args) {
+		failed := make(map[string]string)
+		for _, branch := range branches {
+			failed[branch] = fmt.Sprintf("Error deleting branch %s: %s", branch, errDryRun)
+		}
+		return failed
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	start := time.Now()
+	runErr := cmd.Run()
+	logGitCommand(args, start, runErr)
+
+	deleted := make(map[string]bool)
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if name, ok := strings.CutPrefix(line, "Deleted branch "); ok {
+			name, _, _ = strings.Cut(name, " (was ")
+			deleted[name] = true
+			info("Deleted branch %s", name)
+		}
+	}
+
+	// Attribute each error, and the hint lines after it, to the branch it
+	// names in quotes.
+	errors := make(map[string][]string)
+	var last string
+	for _, line := range strings.Split(strings.TrimSpace(stderr.String()), "\n") {
+		if strings.HasPrefix(line, "error: ") {
+			last = erroredBranch(line, branches)
 		}
+		if last != "" {
+			errors[last] = append(errors[last], line)
+		}
+	}
+
+	failed := make(map[string]string)
+	for _, branch := range branches {
+		if deleted[branch] {
+			continue
+		}
+		output := strings.Join(errors[branch], "\n")
+		if output == "" {
+			output = strings.TrimSpace(stderr.String())
+		}
+		if output == "" && runErr != nil {
+			output = runErr.Error()
+		}
+		failed[branch] = fmt.Sprintf("Error deleting branch %s: %s", branch, output)
 	}
 	return failed
 }
 
-func keepBranches(branchesToKeep []string, force bool) {
+// deleteOptions controls how a selection of branches is confirmed and deleted.
+type deleteOptions struct {
+	force  bool
+	review bool
+	safe   bool
+	noGC   bool
+	atomic bool
+	// verifySigned asks again before force-deleting branches whose tips
+	// are signed.
+	verifySigned bool
+	// smartForce force-deletes only the unmerged branches, after asking
+	// about them separately.
+	smartForce bool
+	record     string
+	// json reports the planned deletions and their results as JSON on
+	// stdout.
+	json bool
+}
+
+// parseDeleteOptions extracts the flags shared by the deleting commands.
+func parseDeleteOptions(args []string, force bool) (deleteOptions, []string) {
+	opts := deleteOptions{}
+	opts.force, args = extractFlag(args, "--force")
+	opts.force = opts.force || force
+	opts.review, args = extractFlag(args, "--review")
+	opts.safe, args = extractFlag(args, "--safe")
+	opts.noGC, args = extractFlag(args, "--no-gc")
+	opts.atomic, args = extractFlag(args, "--atomic")
+	opts.smartForce, args = extractFlag(args, "--smart-force")
+	opts.verifySigned, args = extractFlag(args, "--verify-signed")
+	opts.record, _, args = extractOption(args, "--record")
+	opts.json, args = extractFlag(args, "--json")
+	if opts.json {
+		startJSONOutput()
+	}
+	return opts, args
+}
+
+// keepBranches deletes every selected branch that is not in branchesToKeep.
+func keepBranches(branchesToKeep []string, sel selection, opts deleteOptions) {
 	allBranches, currentBranch, err := listBranches()
 	if err != nil {
-		warn("Error listing branches:", err)
-		os.Exit(1)
+		warn("Error listing branches: %s", err)
+		exit(1)
+	}
+	candidates, err := sel.filter(allBranches)
+	if err != nil {
+		warn("Error selecting branches: %s", err)
+		exit(1)
 	}
 
 	var branchesToDelete []string
-	for _, branch := range allBranches {
-		if branch != "" && !contains(branchesToKeep, branch) {
+	for _, branch := range candidates {
+		if branch != "" && !containsName(branchesToKeep, branch, sel.ignoreCase) {
 			branchesToDelete = append(branchesToDelete, branch)
 		}
 	}
 
-	confirmAndDeleteBranches(branchesToDelete, currentBranch, force)
+	confirmAndDeleteBranches(branchesToDelete, currentBranch, opts)
 }
 
-func confirmAndDeleteBranches(branchesToDelete []string, currentBranch string, force bool) bool {
-	// Filter out the current branch from the branches to delete
-	filteredBranches := filterCurrentBranch(branchesToDelete, currentBranch)
+func confirmAndDeleteBranches(branchesToDelete []string, currentBranch string, opts deleteOptions) bool {
+	filteredBranches, skipped := partitionDeletable(branchesToDelete, currentBranch, opts.safe)
+	var report *deletionReport
+	if opts.json {
+		report = newDeletionReport(filteredBranches, skipped)
+		defer report.write()
+	}
 
 	if len(filteredBranches) == 0 {
+		showSkippedBranches(skipped)
 		status("No branches to delete.")
 		return false
 	}
 
-	yes := confirmBranchesToDelete(filteredBranches)
+	if opts.review {
+		filteredBranches = reviewBranches(filteredBranches)
+		if len(filteredBranches) == 0 {
+			status("No branches selected for deletion.")
+			return false
+		}
+	}
+
+	if opts.force {
+		showLostCommits(filteredBranches, filteredBranches)
+	}
+	warnAboutStashes(filteredBranches)
+
+	filteredBranches, yes := confirmBranchesToDelete(filteredBranches, skipped)
 	if !yes {
 		return false
 	}
 
-	deleteBranches(filteredBranches, force)
+	var forced []string
+	if opts.force {
+		forced = filteredBranches
+	} else if opts.smartForce {
+		filteredBranches, forced = confirmForcedBranches(filteredBranches)
+	}
+
+	if len(forced) > 0 && opts.verifySigned {
+		confirmed := confirmSignedBranches(forced)
+		for _, branch := range forced {
+			if !contains(confirmed, branch) {
+				filteredBranches = removeName(filteredBranches, branch)
+			}
+		}
+		forced = confirmed
+	}
+	if len(filteredBranches) == 0 {
+		status("No branches to delete.")
+		return false
+	}
+
+	if opts.record != "" {
+		if err := writeRecoveryScript(opts.record, filteredBranches); err != nil {
+			warn("Error writing recovery script, nothing was deleted: %s", err)
+			return false
+		}
+		status("Recovery script written to %s", opts.record)
+	}
+
+	before, _ := countObjects()
+	var deleted int
+	if opts.atomic {
+		deleted = deleteBranchesAtomically(filteredBranches, len(forced) > 0)
+	} else {
+		normal := filteredBranches
+		for _, branch := range forced {
+			normal = removeName(normal, branch)
+		}
+		if len(normal) > 0 {
+			deleted += deleteBranches(normal, false)
+		}
+		if len(forced) > 0 {
+			deleted += deleteBranches(forced, true)
+		}
+	}
+	if report != nil {
+		report.addResults(filteredBranches)
+	}
+	if !opts.noGC {
+		runMaintenance(deleted, before)
+	}
+	packRefsIfMany(deleted)
+	notifyCleanup(filteredBranches, before)
 	return true
 }
 
@@ -164,44 +644,113 @@ func filterCurrentBranch(branchesToDelete []string, currentBranch string) []stri
 	return filteredBranches
 }
 
-func deleteBranchesByPattern(pattern string, force bool) {
+func deleteSelectedBranches(sel selection, opts deleteOptions) {
 	branches, currentBranch, err := listBranches()
 	if err != nil {
-		log.Fatal("Error listing branches:", err)
+		fatal("Error listing branches:", err)
 	}
 
-	isPrefixWildcard := strings.HasPrefix(pattern, "*")
-	isSuffixWildcard := strings.HasSuffix(pattern, "*")
-	pattern = strings.Trim(pattern, "*")
+	toDelete, err := sel.filter(branches)
+	if err != nil {
+		fatal("Error selecting branches:", err)
+	}
 
-	var toDelete []string
-	for _, branch := range branches {
-		var match bool
-		switch {
-		case isPrefixWildcard && isSuffixWildcard:
-			match = strings.Contains(branch, pattern)
-		case isPrefixWildcard:
-			match = strings.HasSuffix(branch, pattern)
-		case isSuffixWildcard:
-			match = strings.HasPrefix(branch, pattern)
-		default:
-			match = branch == pattern
-		}
+	if len(toDelete) == 0 {
+		status("No branches match the given pattern and filters.")
+		return
+	}
+
+	confirmAndDeleteBranches(toDelete, currentBranch, opts)
+}
 
-		if match {
-			toDelete = append(toDelete, branch)
+// matchesPattern reports whether branch matches pattern, where a leading or
+// trailing '*' matches any suffix or prefix respectively and brace sets such
+// as {tmp,wip}/* match any of their alternatives.
+func matchesPattern(branch, pattern string) bool {
+	if strings.Contains(pattern, "{") {
+		for _, p := range expandBraces(pattern) {
+			if matchesWildcard(branch, p) {
+				return true
+			}
 		}
+		return false
 	}
+	return matchesWildcard(branch, pattern)
+}
 
-	if len(toDelete) == 0 {
-		status("No branches match the given pattern.")
-		return
+// matchesPatternCase is matchesPattern, optionally ignoring letter case.
+func matchesPatternCase(branch, pattern string, ignoreCase bool) bool {
+	if ignoreCase {
+		return matchesPattern(strings.ToLower(branch), strings.ToLower(pattern))
 	}
+	return matchesPattern(branch, pattern)
+}
+
+func matchesWildcard(branch, pattern string) bool {
+	isPrefixWildcard := strings.HasPrefix(pattern, "*")
+	isSuffixWildcard := strings.HasSuffix(pattern, "*")
+	pattern = strings.Trim(pattern, "*")
 
-	confirmAndDeleteBranches(toDelete, currentBranch, force)
+	switch {
+	case isPrefixWildcard && isSuffixWildcard:
+		return strings.Contains(branch, pattern)
+	case isPrefixWildcard:
+		return strings.HasSuffix(branch, pattern)
+	case isSuffixWildcard:
+		return strings.HasPrefix(branch, pattern)
+	default:
+		return branch == pattern
+	}
 }
 
-func deleteBranches(toDelete []string, force bool) {
+// expandBraces expands shell-style brace sets, so "{a,b}/x{1,2}" becomes
+// a/x1, a/x2, b/x1 and b/x2. Braces without a comma are left as they are.
+func expandBraces(pattern string) []string {
+	start := -1
+	depth := 0
+	var commas []int
+	for i, c := range pattern {
+		switch c {
+		case '{':
+			if depth == 0 {
+				start = i
+				commas = nil
+			}
+			depth++
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth > 0 || len(commas) == 0 {
+				continue
+			}
+
+			prefix, suffix := pattern[:start], pattern[i+1:]
+			var expanded []string
+			from := start + 1
+			for _, comma := range append(commas, i) {
+				alternative := prefix + pattern[from:comma] + suffix
+				expanded = append(expanded, expandBraces(alternative)...)
+				from = comma + 1
+			}
+			return expanded
+		}
+	}
+	return []string{pattern}
+}
+
+// deleteBranches deletes toDelete, reports the outcome and returns how many
+// branches were deleted.
+func deleteBranches(toDelete []string, force bool) int {
+	if dryRun {
+		showDryRunDeletion(toDelete)
+		return 0
+	}
 	failed := _deleteBranches(toDelete, force)
 	deletedCount := len(toDelete) - len(failed)
 
@@ -210,6 +759,15 @@ func deleteBranches(toDelete []string, force bool) {
 		for branch, errMsg := range failed {
 			warn("Branch: %s - Error: %s", branch, errMsg)
 		}
+
+		if !force {
+			var failedBranches []string
+			for branch := range failed {
+				failedBranches = append(failedBranches, branch)
+			}
+			sort.Strings(failedBranches)
+			showLostCommits(failedBranches, failedBranches)
+		}
 	}
 
 	deletedCountStr := "branches"
@@ -228,62 +786,369 @@ func deleteBranches(toDelete []string, force bool) {
 	if failDeleteCount > 0 {
 		warn("%d %s were not deleted due to errors.\n", failDeleteCount, deletedCountStr)
 	}
+	return deletedCount
+}
+
+// confirmBranchesToDelete previews toDelete, grouped into merged and
+// unmerged branches, with the selected branches that are skipped, and asks
+// for confirmation. The user may instead edit the selection, so it returns
+// the branches that were finally confirmed.
+func confirmBranchesToDelete(toDelete []string, skipped []skippedBranch) ([]string, bool) {
+	infos, err := loadBranchInfos(toDelete)
+	if err != nil {
+		warn("Error reading branch details: %s", err)
+		exit(1)
+	}
+	warnIfFetchIsStale()
+	warnIfShallow()
+
+	prs := openPullRequestsOrWarn()
+
+	for {
+		shown := previewDeletion(toDelete, skipped, infos, prs)
+		switch askDeletion(true) {
+		case "yes":
+			return toDelete, true
+		case "no":
+			return nil, false
+		}
+
+		toDelete = excludeBranches(shown)
+		if len(toDelete) == 0 {
+			status("No branches left to delete.")
+			return nil, false
+		}
+	}
 }
 
-func confirmBranchesToDelete(toDelete []string) bool {
+// previewDeletion shows the branches about to be deleted and returns them
+// in the order they were numbered.
+func previewDeletion(toDelete []string, skipped []skippedBranch, infos map[string]branchInfo, prs map[string]int) []string {
 	if len(toDelete) == 1 {
 		title("The following branch matches the pattern and will be deleted:")
 	} else {
 		title("The following branches match the pattern and will be deleted:")
 	}
 
+	var merged, unmerged []string
 	for _, branch := range toDelete {
-		info(branch)
+		if infos[branch].Merged {
+			merged = append(merged, branch)
+		} else {
+			unmerged = append(unmerged, branch)
+		}
+	}
+	withPRs := showBranchGroup("Merged (safe)", merged, 1, len(toDelete), infos, prs)
+	withPRs = append(withPRs, showBranchGroup("Unmerged (needs force)", unmerged, len(merged)+1, len(toDelete), infos, prs)...)
+	showSkippedBranches(skipped)
+
+	if len(withPRs) > 0 {
+		warn("\nWARNING: %d of these branches have open pull requests: %s", len(withPRs), strings.Join(withPRs, ", "))
+		warn("Make sure that work is not still in review before deleting it.")
 	}
-	return confirmDeletion()
+	return append(merged, unmerged...)
+}
+
+type listOptions struct {
+	selection
+	count bool
+	// groupBy is "author" or "label" to list the branches in groups.
+	groupBy string
+	long    bool
+	vv      bool
+	// page and perPage select one page of the sorted list; perPage 0
+	// lists everything.
+	page    int
+	perPage int
 }
 
-func listSortedBranches() {
-	branches, _, err := listBranches()
+func listSortedBranches(opts listOptions) {
+	all, _, err := listBranches()
+	var branches []string
+	if err == nil {
+		branches, err = opts.filter(all)
+	}
 	if err != nil {
 		warn("Error listing branches: %s", err)
-		os.Exit(1)
+		exit(1)
+	}
+
+	if opts.count {
+		fmt.Println(len(branches))
+		return
+	}
+	warnIfFetchIsStale()
+	warnIfShallow()
+
+	switch opts.groupBy {
+	case "author":
+		listBranchesByAuthor(branches)
+		return
+	case "label":
+		listBranchesByLabel(branches)
+		return
+	}
+
+	sortBranches(branches)
+	// Number branches by their place in the full list, even when filtered,
+	// so the numbers shown are the indexes other commands accept.
+	indexes := branchIndexes(all)
+	if opts.perPage > 0 {
+		defer showPageFooter(opts.page, opts.perPage, len(branches))
+		branches = pageOf(branches, opts.page, opts.perPage)
+	}
+
+	if opts.long {
+		listBranchesLong(branches, indexes)
+		return
+	}
+
+	if opts.vv {
+		listBranchesVV(branches, indexes)
+		return
+	}
+
+	var violations map[string]ageViolation
+	if len(config().Policies.MaxAge) > 0 {
+		dates, err := branchCommitDates()
+		if err != nil {
+			warn("Error listing branches: %s", err)
+			exit(1)
+		}
+		violations = ageViolations(dates)
 	}
 
-	sort.Strings(branches)
 	titleString := "Branches"
 	if len(branches) == 1 {
 		titleString = "Branch"
 	}
 	title(titleString)
-	for i, branch := range branches {
-		info("%2d. %s", i+1, branch)
+	prs := openPullRequestsOrWarn()
+	labels := branchLabels()
+
+	for _, branch := range branches {
+		i := indexes[branch]
+		name := branch
+		if number, ok := prs[branch]; ok {
+			name = fmt.Sprintf("%s [PR #%d]", branch, number)
+		}
+		name += labelSuffix(labels[branch])
+
+		violation, tooOld := violations[branch]
+		switch {
+		case plainOutput && tooOld:
+			info("branch %d of %d: %s, policy violation: %s", i, len(all), name, violation)
+		case plainOutput:
+			info("branch %d of %d: %s", i, len(all), name)
+		case tooOld:
+			warn("%2d. %s  (over %s max age)", i, name, formatDuration(violation.limit))
+		default:
+			info("%2d. %s", i, name)
+		}
+	}
+}
+
+// gitLines runs git with args and returns the non-empty lines of its output.
+func gitLines(args ...string) ([]string, error) {
+	output, err := gitOutput(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// gitRun runs git with args, passing its output through to the user along
+// with the other messages, which --json moves to stderr.
+func gitRun(args ...string) error {
+	if refusedInDryRun(args) {
+		warn("Skipped 'git %s': %s", strings.Join(args, " "), errDryRun)
+		return errDryRun
+	}
+	start := time.Now()
+	cmd := exec.Command("git", args...)
+	cmd.Stdout = color.Output
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	logGitCommand(args, start, err)
+	return err
+}
+
+// baseBranches returns the branches that merged checks compare against: the
+// --base options, the configured bases, or the detected default branch. A
+// branch counts as merged if any of them contains it.
+func baseBranches() []string {
+	if len(baseOverrides) > 0 {
+		return baseOverrides
+	}
+	if len(config().Base) > 0 {
+		return config().Base
 	}
+	return []string{defaultBaseBranch()}
+}
+
+// baseBranch returns the primary base branch, for operations such as
+// branching or comparing that need exactly one.
+func baseBranch() string {
+	return baseBranches()[0]
+}
+
+// defaultBaseBranch guesses the branch that work is merged into: the branch
+// the remote's HEAD points at, falling back to main, master and finally HEAD.
+func defaultBaseBranch() string {
+	if gitBinaryMissing() {
+		return goGitDefaultBaseBranch()
+	}
+	if lines, err := gitLines("symbolic-ref", "--short", "refs/remotes/"+remoteName+"/HEAD"); err == nil && len(lines) > 0 {
+		local := strings.TrimPrefix(lines[0], remoteName+"/")
+		if branchExists(local) {
+			return local
+		}
+		return lines[0]
+	}
+
+	for _, candidate := range []string{"main", "master"} {
+		if branchExists(candidate) {
+			return candidate
+		}
+	}
+	return "HEAD"
+}
+
+// gitCommonDir returns the absolute path of the repository's .git directory,
+// shared by all of its worktrees.
+func gitCommonDir() (string, error) {
+	if gitBinaryMissing() {
+		return goGitCommonDir()
+	}
+	lines, err := gitLines("rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil || len(lines) == 0 {
+		return "", fmt.Errorf("not a git repository")
+	}
+	return lines[0], nil
+}
+
+func remoteExists(remote string) bool {
+	if noGitBinary {
+		return goGitRemoteExists(remote)
+	}
+	remotes, err := gitLines("remote")
+	return err == nil && contains(remotes, remote)
+}
+
+func branchExists(branch string) bool {
+	return gitQuiet("show-ref", "--verify", "--quiet", "refs/heads/"+branch) == nil
+}
+
+// mergedBranches returns the local branches whose tips are reachable from base.
+func mergedBranches(base string) ([]string, error) {
+	return gitLines("branch", "--merged", base, "--format=%(refname:short)")
+}
+
+// mergedIntoAnyBase returns the local branches merged into at least one of
+// the base branches.
+func mergedIntoAnyBase() ([]string, error) {
+	var merged []string
+	for _, base := range baseBranches() {
+		branches, err := mergedBranches(base)
+		if err != nil {
+			return nil, fmt.Errorf("cannot check branches merged into %s: %w", base, err)
+		}
+		for _, branch := range branches {
+			if !contains(merged, branch) {
+				merged = append(merged, branch)
+			}
+		}
+	}
+	return merged, nil
+}
+
+// branchTips maps each local branch to the SHA of its tip commit.
+func branchTips() (map[string]string, error) {
+	lines, err := gitLines("for-each-ref", "--format=%(objectname) %(refname:short)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+
+	tips := make(map[string]string, len(lines))
+	for _, line := range lines {
+		sha, branch, _ := strings.Cut(line, " ")
+		tips[branch] = sha
+	}
+	return tips, nil
+}
+
+// branchCommitDates maps each local branch to the committer date of its tip.
+func branchCommitDates() (map[string]time.Time, error) {
+	lines, err := gitLines("for-each-ref", "--format=%(committerdate:unix) %(refname:short)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+
+	dates := make(map[string]time.Time, len(lines))
+	for _, line := range lines {
+		unix, branch, _ := strings.Cut(line, " ")
+		seconds, err := strconv.ParseInt(unix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected for-each-ref output %q", line)
+		}
+		dates[branch] = time.Unix(seconds, 0)
+	}
+	return dates, nil
+}
+
+// sortBranches puts branches in the order `list` shows them, which defines the
+// indexes accepted by commands that take a branch index.
+func sortBranches(branches []string) {
+	sort.Strings(branches)
+}
+
+// branchIndexes returns the 1-based index of each of branches, all the local
+// branches, in the order `list` shows them.
+func branchIndexes(branches []string) map[string]int {
+	sorted := append([]string(nil), branches...)
+	sortBranches(sorted)
+	indexes := make(map[string]int, len(sorted))
+	for i, branch := range sorted {
+		indexes[branch] = i + 1
+	}
+	return indexes
 }
 
 func listBranches() ([]string, string, error) {
-	cmd := exec.Command("git", "branch")
-	output, err := cmd.Output()
+	infos, err := readBranchRefs()
 	if err != nil {
 		return nil, "", err
 	}
 
-	branches := strings.Split(string(output), "\n")
 	var currentBranch string
-	var nonEmptyBranches []string
-
-	for _, branch := range branches {
-		branch = strings.TrimSpace(branch)
-		if strings.HasPrefix(branch, "*") {
-			branch = strings.TrimSpace(branch[1:])
-			currentBranch = branch
-		}
-		if branch != "" {
-			nonEmptyBranches = append(nonEmptyBranches, branch)
+	branches := make([]string, 0, len(infos))
+	for _, b := range infos {
+		if b.Current {
+			currentBranch = b.Name
 		}
+		branches = append(branches, b.Name)
 	}
+	return branches, currentBranch, nil
+}
 
-	return nonEmptyBranches, currentBranch, nil
+// containsName is contains, optionally ignoring letter case.
+func containsName(names []string, name string, ignoreCase bool) bool {
+	if !ignoreCase {
+		return contains(names, name)
+	}
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
 }
 
 func contains(slice []string, item string) bool {
@@ -297,12 +1162,23 @@ func contains(slice []string, item string) bool {
 }
 
 func deleteBranch(branch string, force bool) error {
-	cmd := exec.Command("git", "branch", "-d", branch)
+	if dryRun {
+		info("Would delete branch %s", branch)
+		return nil
+	}
+	flag := "-d"
 	if force {
-		cmd = exec.Command("git", "branch", "-D", branch)
+		flag = "-D"
+	}
+	run, err := trashBranches([]string{branch})
+	if err != nil {
+		warn("Cannot keep %s for undo: %s", branch, err)
 	}
-	output, err := cmd.CombinedOutput()
+	output, err := gitCombinedOutput("branch", flag, branch)
 	if err != nil {
+		if run != "" {
+			untrashBranches(run, []string{branch})
+		}
 		return fmt.Errorf("Error deleting branch %s: %s", branch, output)
 	}
 	info("Deleted branch %s", branch)
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+// newTestRepo creates a repository with a commit on main and the given
+// branches at it, and makes it the working directory for the test.
+func newTestRepo(t *testing.T, branches ...string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(name, "Test")
+	}
+	for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "test@example.com")
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+		loadedConfig = nil
+	})
+	loadedConfig = nil
+
+	testGit(t, "init", "--quiet", "--initial-branch=main")
+	testGit(t, "commit", "--quiet", "--allow-empty", "-m", "initial")
+	for _, branch := range branches {
+		testGit(t, "branch", branch)
+	}
+}
+
+// testGit runs git with args in the working directory, failing the test if
+// it fails.
+func testGit(t *testing.T, args ...string) {
+	t.Helper()
+	if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %s\n%s", strings.Join(args, " "), err, output)
+	}
+}
+
+// captureStdout runs f with stdout, and color.Output as it is by default,
+// going to a pipe, and returns what was written to it.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	savedStdout, savedOutput := os.Stdout, color.Output
+	os.Stdout, color.Output = w, w
+	defer func() {
+		os.Stdout, color.Output = savedStdout, savedOutput
+	}()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	f()
+	w.Close()
+	return <-done
+}
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"feature/*", []string{"feature/*"}},
+		{"{fix,hotfix}/*", []string{"fix/*", "hotfix/*"}},
+		{"{a,b}/x{1,2}", []string{"a/x1", "a/x2", "b/x1", "b/x2"}},
+		{"{a,b{1,2}}", []string{"a", "b1", "b2"}},
+		{"{a,}x", []string{"ax", "x"}},
+		// Braces without a comma, or unbalanced, are left alone.
+		{"release/{1}", []string{"release/{1}"}},
+		{"tmp/{a,b", []string{"tmp/{a,b"}},
+		{"tmp/a}", []string{"tmp/a}"}},
+	}
+	for _, tt := range tests {
+		if got := expandBraces(tt.pattern); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandBraces(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestChunkArgs(t *testing.T) {
+	tests := []struct {
+		args  []string
+		limit int
+		want  [][]string
+	}{
+		{nil, 10, nil},
+		{[]string{"aa", "bb", "cc"}, 6, [][]string{{"aa", "bb"}, {"cc"}}},
+		{[]string{"aa", "bb", "cc"}, 100, [][]string{{"aa", "bb", "cc"}}},
+		// An argument over the limit still goes, on its own.
+		{[]string{"a", "much-too-long", "b"}, 4, [][]string{{"a"}, {"much-too-long"}, {"b"}}},
+	}
+	for _, tt := range tests {
+		if got := chunkArgs(tt.args, tt.limit); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("chunkArgs(%q, %d) = %q, want %q", tt.args, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestDeleteBranchBatchAttributesErrors(t *testing.T) {
+	newTestRepo(t, "merged", "feature/x'y")
+	testGit(t, "switch", "--quiet", "-c", "unmerged")
+	testGit(t, "commit", "--quiet", "--allow-empty", "-m", "work")
+	testGit(t, "switch", "--quiet", "main")
+
+	var failed map[string]string
+	captureStdout(t, func() {
+		failed = deleteBranchBatch([]string{"merged", "unmerged", "missing", "feature/x'y"}, false)
+	})
+
+	want := map[string]string{
+		"unmerged": "not fully merged",
+		"missing":  "not found",
+	}
+	if len(failed) != len(want) {
+		t.Errorf("failed = %q, want errors for %d branches", failed, len(want))
+	}
+	for branch, reason := range want {
+		if !strings.Contains(failed[branch], reason) {
+			t.Errorf("error for %s = %q, want it to mention %q", branch, failed[branch], reason)
+		}
+	}
+	if !branchExists("unmerged") || branchExists("merged") {
+		t.Error("the wrong branches were deleted")
+	}
+}
+
+func TestSplitAtDoubleDash(t *testing.T) {
+	tests := []struct {
+		args         []string
+		head, passed []string
+	}{
+		{[]string{"--dry-run", "delete", "tmp/*"}, []string{"--dry-run", "delete", "tmp/*"}, nil},
+		{[]string{"foreach-submodule", "--", "delete", "--dry-run"}, []string{"foreach-submodule"}, []string{"--", "delete", "--dry-run"}},
+		{[]string{"--", "--"}, []string{}, []string{"--", "--"}},
+	}
+	for _, tt := range tests {
+		head, passed := splitAtDoubleDash(tt.args)
+		if !reflect.DeepEqual(head, tt.head) || !reflect.DeepEqual(passed, tt.passed) {
+			t.Errorf("splitAtDoubleDash(%q) = %q, %q; want %q, %q", tt.args, head, passed, tt.head, tt.passed)
+		}
+	}
+}
+
+func TestErroredBranch(t *testing.T) {
+	branches := []string{"a", "x/a", "it's", "it", "it's'done"}
+	tests := []struct{ line, want string }{
+		{"error: branch 'a' not found.", "a"},
+		{"error: branch 'x/a' not found", "x/a"},
+		{"error: the branch 'it's' is not fully merged.", "it's"},
+		{"error: the branch 'it's'done' is not fully merged", "it's'done"},
+		{"error: cannot delete branch 'a' used by worktree at '/tmp/x/a'", "a"},
+		{"error: branch 'b' not found.", ""},
+		{"error: something else", ""},
+	}
+	for _, tt := range tests {
+		if got := erroredBranch(tt.line, branches); got != tt.want {
+			t.Errorf("erroredBranch(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
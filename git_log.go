@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gitLogEntry is one line of the --log-file log, describing a git command.
+type gitLogEntry struct {
+	Time       time.Time `json:"time"`
+	Dir        string    `json:"dir"`
+	Args       []string  `json:"args"`
+	DurationMS int64     `json:"duration_ms"`
+	ExitCode   int       `json:"exit_code"`
+	Error      string    `json:"error,omitempty"`
+}
+
+var (
+	// logFilePath is the absolute path of the command log, if any.
+	logFilePath string
+	gitLog      *json.Encoder
+)
+
+// openGitLog starts appending a JSON line for every git command to path.
+func openGitLog(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(abs, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	logFilePath = abs
+	gitLog = json.NewEncoder(f)
+	return nil
+}
+
+// logGitCommand records a git command that started at start and finished
+// with err.
+func logGitCommand(args []string, start time.Time, err error) {
+	if gitLog == nil {
+		return
+	}
+
+	entry := gitLogEntry{
+		Time:       start,
+		Args:       args,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	entry.Dir, _ = os.Getwd()
+	if err != nil {
+		entry.ExitCode = -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			entry.ExitCode = exitErr.ExitCode()
+		}
+		entry.Error = err.Error()
+	}
+	// The log is a debugging aid, so a failed write must not stop the
+	// command being logged.
+	_ = gitLog.Encode(entry)
+}
+
+// errDryRun is returned instead of running a git command that would change
+// the repository while --dry-run is set. It is checked below every command,
+// so that no code path can change anything during a dry run.
+var errDryRun = errors.New("not run because of --dry-run")
+
+// refusedInDryRun reports whether git with args must not run because
+// --dry-run is set.
+func refusedInDryRun(args []string) bool {
+	return dryRun && gitMutates(args)
+}
+
+// gitMutates reports whether git with args may change refs, the index, the
+// working tree or config. Fetching only updates the copy of the remote, so
+// it counts as reading.
+func gitMutates(args []string) bool {
+	// Skip global options such as -C dir.
+	for len(args) > 1 && (args[0] == "-C" || args[0] == "-c") {
+		args = args[2:]
+	}
+	if len(args) == 0 {
+		return false
+	}
+	verb, rest := args[0], args[1:]
+	var positional []string
+	for _, arg := range rest {
+		if !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+		}
+	}
+	has := func(flags ...string) bool {
+		for _, arg := range rest {
+			for _, flag := range flags {
+				if arg == flag || strings.HasPrefix(arg, flag+"=") {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	switch verb {
+	case "branch":
+		if has("-d", "-D", "--delete", "-m", "-M", "--move", "-c", "-C", "--copy", "-f", "--force",
+			"-u", "--set-upstream-to", "--unset-upstream", "--edit-description") {
+			return true
+		}
+		if has("-l", "--list", "--show-current", "--merged", "--no-merged", "--contains", "--no-contains",
+			"--points-at", "--format", "-v", "-vv", "-a", "-r", "--all", "--remotes") {
+			return false
+		}
+		return len(positional) > 0
+	case "config":
+		return !has("--get", "--get-all", "--get-regexp", "--get-urlmatch", "-l", "--list")
+	case "tag":
+		return !has("-l", "--list", "--points-at", "--contains", "--merged", "--no-merged") && len(positional) > 0
+	case "symbolic-ref":
+		return has("-d", "--delete") || len(positional) > 1
+	case "reflog":
+		return len(positional) > 0 && positional[0] != "show" && !has("-n", "--dry-run")
+	case "remote":
+		return len(positional) > 0 && positional[0] != "get-url" && positional[0] != "show"
+	case "stash":
+		return len(positional) == 0 || (positional[0] != "list" && positional[0] != "show")
+	case "worktree":
+		return len(positional) > 0 && positional[0] != "list"
+	case "submodule":
+		return len(positional) > 0 && positional[0] != "foreach" && positional[0] != "status"
+	case "push":
+		return !has("-n", "--dry-run")
+	case "update-ref", "switch", "checkout", "merge", "rebase", "cherry-pick", "commit", "reset",
+		"gc", "maintenance", "pack-refs", "prune", "notes", "replace":
+		return true
+	}
+	return false
+}
+
+// gitOutput runs git and returns its standard output.
+func gitOutput(args ...string) ([]byte, error) {
+	if refusedInDryRun(args) {
+		return nil, errDryRun
+	}
+	start := time.Now()
+	output, err := exec.Command("git", args...).Output()
+	logGitCommand(args, start, err)
+	return output, err
+}
+
+// gitCombinedOutput runs git and returns its standard output and error
+// together.
+func gitCombinedOutput(args ...string) ([]byte, error) {
+	if refusedInDryRun(args) {
+		return []byte(errDryRun.Error()), errDryRun
+	}
+	start := time.Now()
+	output, err := exec.Command("git", args...).CombinedOutput()
+	logGitCommand(args, start, err)
+	return output, err
+}
+
+// gitWithInput runs git with input on stdin and returns its standard output
+// and error together.
+func gitWithInput(input string, args ...string) ([]byte, error) {
+	if refusedInDryRun(args) {
+		return []byte(errDryRun.Error()), errDryRun
+	}
+	start := time.Now()
+	cmd := exec.Command("git", args...)
+	cmd.Stdin = strings.NewReader(input)
+	output, err := cmd.CombinedOutput()
+	logGitCommand(args, start, err)
+	return output, err
+}
+
+// gitQuiet runs git, discarding its output.
+func gitQuiet(args ...string) error {
+	if refusedInDryRun(args) {
+		return errDryRun
+	}
+	start := time.Now()
+	err := exec.Command("git", args...).Run()
+	logGitCommand(args, start, err)
+	return err
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const githubPageSize = 100
+
+type githubPull struct {
+	Number int `json:"number"`
+	Head   struct {
+		Ref  string `json:"ref"`
+		Repo *struct {
+			FullName string `json:"full_name"`
+		} `json:"repo"`
+	} `json:"head"`
+}
+
+// githubAPIURL returns the API base URL for the repository's host.
+func githubAPIURL(hosting HostingConfig, loc repoLocation) string {
+	if hosting.APIURL != "" {
+		return strings.TrimSuffix(hosting.APIURL, "/")
+	}
+	if loc.host == "github.com" {
+		return "https://api.github.com"
+	}
+	return "https://" + loc.host + "/api/v3"
+}
+
+// githubToken returns the token for host: the configured one, GH_TOKEN or
+// GITHUB_TOKEN, the one stored with `auth login`, or the one the gh CLI is
+// logged in with.
+func githubToken(hosting HostingConfig, host string) string {
+	token, _ := githubTokenSource(hosting, host)
+	return token
+}
+
+// githubTokenSource is githubToken, also saying where the token came from.
+func githubTokenSource(hosting HostingConfig, host string) (token, source string) {
+	if hosting.Token != "" {
+		return hosting.Token, "hosting.token in the config"
+	}
+	for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN"} {
+		if token := os.Getenv(name); token != "" {
+			return token, name
+		}
+	}
+	if token, err := keychainGet("github:" + host); err == nil {
+		return token, keychainName()
+	}
+	if token := ghCLIToken(host); token != "" {
+		return token, "gh CLI"
+	}
+	return "", ""
+}
+
+// githubOpenPullRequests maps the head branches of open pull requests coming
+// from the remote's own repository to their PR numbers.
+func githubOpenPullRequests(hosting HostingConfig) (map[string]int, error) {
+	loc, err := remoteLocation(remoteName)
+	if err != nil {
+		return nil, err
+	}
+	fullName := loc.owner + "/" + loc.name
+	client := newHTTPClient(15 * time.Second)
+
+	prs := make(map[string]int)
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/repos/%s/pulls?state=open&per_page=%d&page=%d", githubAPIURL(hosting, loc), fullName, githubPageSize, page)
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if token := githubToken(hosting, loc.host); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var pulls []githubPull
+		err = json.NewDecoder(resp.Body).Decode(&pulls)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("GitHub API returned %s", resp.Status)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pull := range pulls {
+			if pull.Head.Repo != nil && strings.EqualFold(pull.Head.Repo.FullName, fullName) {
+				prs[pull.Head.Ref] = pull.Number
+			}
+		}
+		if len(pulls) < githubPageSize {
+			return prs, nil
+		}
+	}
+}
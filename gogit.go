@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// noGitBinary makes remote operations talk to the remote through go-git's
+// transports instead of running git, from --no-git-binary or when git is not
+// installed. Authentication uses ssh-agent for SSH remotes and the
+// configured credential helper for HTTPS remotes.
+var noGitBinary bool
+
+// gitBinaryMissing reports whether git cannot be found on the PATH.
+func gitBinaryMissing() bool {
+	_, err := exec.LookPath("git")
+	return err != nil
+}
+
+// openGoGitRepository opens the repository containing the working
+// directory, including from a linked worktree.
+func openGoGitRepository() (*git.Repository, error) {
+	return git.PlainOpenWithOptions(".", &git.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+}
+
+// goGitRemote returns remote and the credentials to use with it.
+func goGitRemote(name string) (*git.Remote, transport.AuthMethod, error) {
+	repo, err := openGoGitRepository()
+	if err != nil {
+		return nil, nil, err
+	}
+	remote, err := repo.Remote(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unknown remote %s", name)
+	}
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return nil, nil, fmt.Errorf("remote %s has no URL", name)
+	}
+	auth, err := goGitAuth(repo, urls[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	if gitBinaryMissing() {
+		// go-git runs git-upload-pack and git-receive-pack for local paths;
+		// serve them in process instead.
+		client.InstallProtocol("file", server.DefaultServer)
+	}
+	return remote, auth, nil
+}
+
+// goGitAuth returns the credentials for rawURL: ssh-agent for SSH, the
+// credential helper for HTTP(S), and none for local paths.
+func goGitAuth(repo *git.Repository, rawURL string) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch endpoint.Protocol {
+	case "ssh":
+		user := endpoint.User
+		if user == "" {
+			user = "git"
+		}
+		return ssh.NewSSHAgentAuth(user)
+	case "http", "https":
+		if endpoint.User != "" && endpoint.Password != "" {
+			return &http.BasicAuth{Username: endpoint.User, Password: endpoint.Password}, nil
+		}
+		user, password, err := fillCredential(repo, endpoint)
+		if err != nil || password == "" {
+			// Public repositories need no credentials.
+			return nil, nil
+		}
+		return &http.BasicAuth{Username: user, Password: password}, nil
+	}
+	return nil, nil
+}
+
+// fillCredential asks the configured credential helper for the username and
+// password of endpoint, speaking git's credential helper protocol.
+func fillCredential(repo *git.Repository, endpoint *transport.Endpoint) (string, string, error) {
+	cfg, err := repo.ConfigScoped(gitconfig.GlobalScope)
+	if err != nil {
+		return "", "", err
+	}
+	helper := cfg.Raw.Section("credential").Option("helper")
+	if helper == "" {
+		return "", "", errors.New("no credential helper configured")
+	}
+
+	var cmd *exec.Cmd
+	switch {
+	case strings.HasPrefix(helper, "!"):
+		cmd = exec.Command("sh", "-c", helper[1:]+" get")
+	case filepath.IsAbs(helper):
+		cmd = exec.Command("sh", "-c", helper+" get")
+	default:
+		cmd = exec.Command("sh", "-c", "git-credential-"+helper+" get")
+	}
+
+	request := fmt.Sprintf("protocol=%s\nhost=%s\n", endpoint.Protocol, endpoint.Host)
+	if endpoint.Port != 0 && endpoint.Port != 80 && endpoint.Port != 443 {
+		request = fmt.Sprintf("protocol=%s\nhost=%s:%d\n", endpoint.Protocol, endpoint.Host, endpoint.Port)
+	}
+	if endpoint.User != "" {
+		request += "username=" + endpoint.User + "\n"
+	}
+	cmd.Stdin = strings.NewReader(request + "\n")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("credential helper %s failed: %w", helper, err)
+	}
+
+	user, password := endpoint.User, ""
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		switch key {
+		case "username":
+			user = value
+		case "password":
+			password = value
+		}
+	}
+	return user, password, nil
+}
+
+// goGitLsRemoteBranches is lsRemoteBranches using go-git.
+func goGitLsRemoteBranches(name string) (map[string]string, error) {
+	remote, auth, err := goGitRemote(name)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		return nil, err
+	}
+
+	branches := make(map[string]string)
+	for _, ref := range refs {
+		if ref.Name().IsBranch() {
+			branches[ref.Name().Short()] = ref.Hash().String()
+		}
+	}
+	return branches, nil
+}
+
+// goGitDeleteRemoteBranches deletes branches from the remote using go-git,
+// one push each so every branch gets its own result, and returns the error
+// for each branch, empty for those deleted.
+func goGitDeleteRemoteBranches(name string, branches []string) map[string]string {
+	results := make(map[string]string, len(branches))
+	if dryRun {
+		for _, branch := range branches {
+			results[branch] = errDryRun.Error()
+		}
+		return results
+	}
+	remote, auth, err := goGitRemote(name)
+	if err != nil {
+		for _, branch := range branches {
+			results[branch] = err.Error()
+		}
+		return results
+	}
+
+	for _, branch := range branches {
+		spec := gitconfig.RefSpec(":refs/heads/" + branch)
+		err := remote.Push(&git.PushOptions{RemoteName: name, RefSpecs: []gitconfig.RefSpec{spec}, Auth: auth})
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			results[branch] = err.Error()
+		} else {
+			results[branch] = ""
+		}
+	}
+	return results
+}
+
+// goGitRemoteExists is remoteExists using go-git.
+func goGitRemoteExists(name string) bool {
+	repo, err := openGoGitRepository()
+	if err != nil {
+		return false
+	}
+	_, err = repo.Remote(name)
+	return err == nil
+}
+
+// goGitFetch is git fetch --prune using go-git. This go-git cannot prune
+// while fetching, so the remote-tracking branches whose branch is gone from
+// the remote are removed afterwards.
+func goGitFetch(name string) error {
+	remote, auth, err := goGitRemote(name)
+	if err != nil {
+		return err
+	}
+	err = remote.Fetch(&git.FetchOptions{RemoteName: name, Auth: auth})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+
+	remoteRefs, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		return err
+	}
+	live := make(map[string]bool)
+	for _, ref := range remoteRefs {
+		if ref.Name().IsBranch() {
+			live[ref.Name().Short()] = true
+		}
+	}
+
+	repo, err := openGoGitRepository()
+	if err != nil {
+		return err
+	}
+	refs, err := repo.References()
+	if err != nil {
+		return err
+	}
+	prefix := "refs/remotes/" + name + "/"
+	var stale []plumbing.ReferenceName
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		branch, ok := strings.CutPrefix(ref.Name().String(), prefix)
+		if ok && branch != "HEAD" && !live[branch] {
+			stale = append(stale, ref.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, ref := range stale {
+		if err := repo.Storer.RemoveReference(ref); err != nil {
+			return fmt.Errorf("cannot prune %s: %w", ref, err)
+		}
+	}
+	return nil
+}
+
+// goGitRemoteTrackingRefs lists the remote-tracking branches of remote as
+// git for-each-ref does with the format
+// "%(refname) %(objectname) %(committerdate:unix)".
+func goGitRemoteTrackingRefs(name string) ([]string, error) {
+	repo, err := openGoGitRepository()
+	if err != nil {
+		return nil, err
+	}
+	refs, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+	prefix := "refs/remotes/" + name + "/"
+	var lines []string
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference || !strings.HasPrefix(ref.Name().String(), prefix) {
+			return nil
+		}
+		commit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %w", ref.Name(), err)
+		}
+		lines = append(lines, fmt.Sprintf("%s %s %d", ref.Name(), ref.Hash(), commit.Committer.When.Unix()))
+		return nil
+	})
+	sort.Strings(lines)
+	return lines, err
+}
+
+// goGitDefaultBaseBranch is defaultBaseBranch using go-git.
+func goGitDefaultBaseBranch() string {
+	repo, err := openGoGitRepository()
+	if err != nil {
+		return "HEAD"
+	}
+	hasBranch := func(name string) bool {
+		_, err := repo.Reference(plumbing.NewBranchReferenceName(name), false)
+		return err == nil
+	}
+	if head, err := repo.Reference(plumbing.NewRemoteHEADReferenceName(remoteName), false); err == nil && head.Type() == plumbing.SymbolicReference {
+		target := head.Target().Short()
+		if local := strings.TrimPrefix(target, remoteName+"/"); hasBranch(local) {
+			return local
+		}
+		return target
+	}
+	for _, candidate := range []string{"main", "master"} {
+		if hasBranch(candidate) {
+			return candidate
+		}
+	}
+	return "HEAD"
+}
+
+// goGitCommonDir is gitCommonDir using go-git.
+func goGitCommonDir() (string, error) {
+	repo, err := openGoGitRepository()
+	if err != nil {
+		return "", fmt.Errorf("not a git repository")
+	}
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return "", fmt.Errorf("not a git repository")
+	}
+	return filepath.Abs(storage.Filesystem().Root())
+}
+
+// goGitConfigLines reads the keys of section from the system, global and
+// repository config as git config --get-regexp shows them: "section.key
+// value" or "section.subsection.key value".
+func goGitConfigLines(section string) ([]string, error) {
+	repo, err := openGoGitRepository()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := repo.ConfigScoped(gitconfig.SystemScope)
+	if err != nil {
+		return nil, err
+	}
+	s := cfg.Raw.Section(section)
+	var lines []string
+	for _, option := range s.Options {
+		lines = append(lines, section+"."+strings.ToLower(option.Key)+" "+option.Value)
+	}
+	for _, sub := range s.Subsections {
+		for _, option := range sub.Options {
+			lines = append(lines, section+"."+sub.Name+"."+strings.ToLower(option.Key)+" "+option.Value)
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// graphEdge links a branch to the branch it was forked from, with how far
+// the two have diverged since.
+type graphEdge struct {
+	parent, child string
+	// ahead counts the child's commits since the fork, behind the
+	// parent's.
+	ahead, behind int
+}
+
+func runGraph(args []string) {
+	mermaid, args := extractFlag(args, "--mermaid")
+	dot, args := extractFlag(args, "--dot")
+	sel, rest, err := parseSelection(args)
+	if err != nil {
+		fatal(err)
+	}
+	if len(rest) > 1 || (mermaid && dot) {
+		usageError("graph")
+	}
+	if len(rest) == 1 {
+		sel.pattern = rest[0]
+	}
+
+	branches, _, err := listBranches()
+	if err == nil {
+		branches, err = sel.filter(branches)
+	}
+	if err != nil {
+		warn("Error selecting branches: %s", err)
+		exit(1)
+	}
+	// The base branches are the roots the rest hang from, so they are
+	// always part of the graph.
+	for _, base := range baseBranches() {
+		if branchExists(base) && !contains(branches, base) {
+			branches = append(branches, base)
+		}
+	}
+	sortBranches(branches)
+
+	edges, err := branchAncestry(branches)
+	if err != nil {
+		warn("Error comparing branches: %s", err)
+		exit(1)
+	}
+	if mermaid {
+		fmt.Print(mermaidGraph(branches, edges))
+	} else {
+		fmt.Print(dotGraph(branches, edges))
+	}
+}
+
+// branchAncestry finds for each branch the one it was most likely forked
+// from: the branch it has made the fewest commits of its own against. Base
+// branches are roots, as are branches sharing no history with the others.
+func branchAncestry(branches []string) ([]graphEdge, error) {
+	bases := baseBranches()
+	var edges []graphEdge
+	for _, child := range branches {
+		if contains(bases, child) {
+			continue
+		}
+
+		var best *graphEdge
+		for _, parent := range branches {
+			if parent == child {
+				continue
+			}
+			behind, ahead, ok, err := divergence(parent, child)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			// A branch that contains every commit of child was forked from
+			// it rather than the other way round, unless it is a base
+			// branch child was merged into. Branches at the same commit
+			// would otherwise be each other's parent, so the later one in
+			// list order is made the child.
+			if ahead == 0 && !contains(bases, parent) && (behind > 0 || branchIndex(branches, parent) > branchIndex(branches, child)) {
+				continue
+			}
+			edge := graphEdge{parent: parent, child: child, ahead: ahead, behind: behind}
+			if best == nil || closerParent(edge, *best, bases) {
+				best = &edge
+			}
+		}
+		if best != nil {
+			edges = append(edges, *best)
+		}
+	}
+	return edges, nil
+}
+
+// closerParent reports whether a is a better guess at the fork point than b:
+// fewer commits on the child since the fork, then a base branch, then fewer
+// commits on the parent since.
+func closerParent(a, b graphEdge, bases []string) bool {
+	if a.ahead != b.ahead {
+		return a.ahead < b.ahead
+	}
+	if aBase, bBase := contains(bases, a.parent), contains(bases, b.parent); aBase != bBase {
+		return aBase
+	}
+	return a.behind < b.behind
+}
+
+// divergence counts the commits on parent and on child since their merge
+// base. ok is false when they share no history.
+func divergence(parent, child string) (behind, ahead int, ok bool, err error) {
+	if gitQuiet("merge-base", "refs/heads/"+parent, "refs/heads/"+child) != nil {
+		return 0, 0, false, nil
+	}
+	lines, err := gitLines("rev-list", "--left-right", "--count", "refs/heads/"+parent+"...refs/heads/"+child)
+	if err != nil || len(lines) == 0 {
+		return 0, 0, false, fmt.Errorf("cannot compare %s with %s", child, parent)
+	}
+	fields := strings.Fields(lines[0])
+	if len(fields) != 2 {
+		return 0, 0, false, fmt.Errorf("cannot compare %s with %s", child, parent)
+	}
+	behind, _ = strconv.Atoi(fields[0])
+	ahead, _ = strconv.Atoi(fields[1])
+	return behind, ahead, true, nil
+}
+
+func branchIndex(branches []string, branch string) int {
+	for i, b := range branches {
+		if b == branch {
+			return i
+		}
+	}
+	return -1
+}
+
+// label shows how far a child has diverged from its parent.
+func (e graphEdge) label() string {
+	return fmt.Sprintf("+%d -%d", e.ahead, e.behind)
+}
+
+// dotGraph renders the graph in Graphviz DOT.
+func dotGraph(branches []string, edges []graphEdge) string {
+	bases := baseBranches()
+	var b strings.Builder
+	b.WriteString("digraph branches {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	for _, branch := range branches {
+		attrs := ""
+		if contains(bases, branch) {
+			attrs = ", style=bold"
+		}
+		fmt.Fprintf(&b, "\t%s [label=%s%s];\n", strconv.Quote(branch), strconv.Quote(branch), attrs)
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "\t%s -> %s [label=%s];\n", strconv.Quote(e.parent), strconv.Quote(e.child), strconv.Quote(e.label()))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// mermaidGraph renders the graph as a Mermaid flowchart. Mermaid node ids
+// cannot hold the characters of branch names, so nodes are numbered.
+func mermaidGraph(branches []string, edges []graphEdge) string {
+	ids := make(map[string]string, len(branches))
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	for i, branch := range branches {
+		ids[branch] = "b" + strconv.Itoa(i)
+		fmt.Fprintf(&b, "    %s[\"%s\"]\n", ids[branch], mermaidText(branch))
+	}
+	sort.SliceStable(edges, func(i, j int) bool {
+		return branchIndex(branches, edges[i].parent) < branchIndex(branches, edges[j].parent)
+	})
+	for _, e := range edges {
+		fmt.Fprintf(&b, "    %s -->|\"%s\"| %s\n", ids[e.parent], e.label(), ids[e.child])
+	}
+	return b.String()
+}
+
+// mermaidText escapes text for a quoted Mermaid label.
+func mermaidText(text string) string {
+	return strings.ReplaceAll(text, `"`, "#quot;")
+}
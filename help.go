@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// optionHelp describes one option a command accepts.
+type optionHelp struct {
+	// name is the option with any other spellings and its value
+	// placeholder, e.g. "-i, --ignore-case" or "--older-than age".
+	name string
+	text string
+}
+
+// spellings returns the ways of writing the option and the placeholder of
+// its value, which is empty if it takes none.
+func (o optionHelp) spellings() ([]string, string) {
+	fields := strings.Fields(o.name)
+	var names []string
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "-") {
+		names = append(names, strings.TrimSuffix(fields[0], ","))
+		fields = fields[1:]
+	}
+	return names, strings.Join(fields, " ")
+}
+
+// usage shows the option as usage text does, e.g. [-i|--ignore-case] or
+// [--older-than age], without the brackets if it is required.
+func (o optionHelp) usage(required bool) string {
+	names, placeholder := o.spellings()
+	text := strings.Join(names, "|")
+	if placeholder != "" {
+		text += " " + placeholder
+	}
+	if required {
+		return text
+	}
+	return "[" + text + "]"
+}
+
+// selectionFlags are the options of parseSelection.
+var selectionFlags = []optionHelp{
+	{"--regex re", "only branches whose whole name matches the regular expression re"},
+	{"-i, --ignore-case", "match patterns and names without regard to case"},
+	{"--invert", "select the branches that do not match the pattern or regex"},
+	{"--merged", "only branches merged into a base branch"},
+	{"--stale", "only branches whose last commit is over 90 days old"},
+	{"--older-than age", "only branches whose last commit is older than age, e.g. 30d"},
+	{"--newer-than age", "only branches whose last commit is newer than age"},
+	{"--author name", "only branches whose last commit is by name"},
+	{"--gone", "only branches whose upstream was deleted"},
+	{"--mine", "only branches whose last commit is yours"},
+	{"--label text", "only branches with a label matching text; repeat for any of several"},
+	{"--where expr", "only branches matching an expression such as 'age > 30d'"},
+}
+
+// deleteFlags are the options of parseDeleteOptions.
+var deleteFlags = []optionHelp{
+	{"--force", "delete branches even if they are not merged"},
+	{"--review", "decide on each branch in turn before confirming"},
+	{"--safe", "only delete branches with no commits missing from a base branch"},
+	{"--atomic", "delete all of the branches or none of them"},
+	{"--smart-force", "delete merged branches normally and ask before forcing the rest"},
+	{"--verify-signed", "ask again before force-deleting branches with signed tips"},
+	{"--record file", "write a script that recreates the deleted branches"},
+	{"--no-gc", "skip the maintenance run after deleting"},
+	{"--json", "print the planned deletions and the result for each branch as JSON"},
+}
+
+func runHelp(args []string) {
+	if len(args) == 0 {
+		showUsage(os.Stdout)
+		return
+	}
+	if len(args) > 1 {
+		usageError("help")
+	}
+
+	c, ok := findCommand(args[0])
+	if !ok || c.hidden {
+		fatalf("Unknown command %s. Use %s.", args[0], quotedCommandList())
+	}
+	showHelp(os.Stdout, c)
+}
+
+// wantsHelp reports whether args ask for the command's help rather than
+// running it.
+func wantsHelp(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if arg == "--help" || arg == "-h" {
+			return true
+		}
+	}
+	return false
+}
+
+// showHelp prints the usage, description, options and examples of c to w.
+func showHelp(w io.Writer, c command) {
+	fmt.Fprintln(w, c.usage())
+	if len(c.aliases) > 0 {
+		fmt.Fprintf(w, "Aliases: %s\n", strings.Join(c.aliases, ", "))
+	}
+
+	fmt.Fprintf(w, "\n%s.\n", capitalize(c.summary))
+	if c.details != "" {
+		fmt.Fprintf(w, "%s\n", c.details)
+	}
+
+	if len(c.flags) > 0 {
+		t := table{}
+		for _, f := range c.flags {
+			t.addRow(f.name, f.text)
+		}
+		fmt.Fprintln(w, "\nOptions:")
+		for _, line := range t.lines() {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+
+	if len(c.examples) > 0 {
+		fmt.Fprintln(w, "\nExamples:")
+		for _, example := range c.examples {
+			if !strings.Contains(example, AppName+" ") {
+				example = AppName + " " + example
+			}
+			fmt.Fprintf(w, "  %s\n", example)
+		}
+	}
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
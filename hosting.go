@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// HostingConfig configures the integration with the service hosting the
+// remote repository. Only the global config and git config can set the
+// provider, token and API URL.
+type HostingConfig struct {
+	// Provider names the hosting service. Pull request lookups support only
+	// "github"; "none" disables them. Empty means github for github.com
+	// remotes when a token is available, such as from the gh CLI.
+	Provider string `yaml:"provider"`
+	// Token is the API token. Prefer `auth login github`, which keeps it
+	// in the OS keychain.
+	Token string `yaml:"token"`
+	// APIURL overrides the API endpoint, e.g. for GitHub Enterprise.
+	APIURL string `yaml:"api_url"`
+	// Open selects what `open` shows by default: branch, pr or compare.
+	Open string `yaml:"open"`
+}
+
+// repoLocation identifies a repository on a hosting service.
+type repoLocation struct {
+	host  string
+	owner string
+	name  string
+}
+
+// remoteLocation parses the URL of remote into its host, owner and repository
+// name. Both scp-like (git@host:owner/repo.git) and URL forms are accepted.
+func remoteLocation(remote string) (repoLocation, error) {
+	lines, err := gitLines("remote", "get-url", remote)
+	if err != nil || len(lines) == 0 {
+		return repoLocation{}, fmt.Errorf("remote %s has no URL", remote)
+	}
+	return parseRemoteURL(lines[0])
+}
+
+func parseRemoteURL(raw string) (repoLocation, error) {
+	var host, path string
+	if u, err := url.Parse(raw); err == nil && u.Scheme != "" && u.Host != "" {
+		host, path = u.Hostname(), u.Path
+	} else if at, rest, ok := strings.Cut(raw, ":"); ok && !strings.Contains(at, "/") {
+		_, host, _ = strings.Cut(at, "@")
+		if host == "" {
+			host = at
+		}
+		path = rest
+	} else {
+		return repoLocation{}, fmt.Errorf("unrecognized remote URL %s", raw)
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return repoLocation{}, fmt.Errorf("unrecognized remote URL %s", raw)
+	}
+	return repoLocation{host: host, owner: path[:i], name: path[i+1:]}, nil
+}
+
+// openPullRequests maps branch names to the number of their open pull
+// request, or returns nil when no hosting integration is configured.
+func openPullRequests() (map[string]int, error) {
+	hosting := config().Hosting
+	switch pullRequestProvider() {
+	case "":
+		return nil, nil
+	case "github":
+		return githubOpenPullRequests(hosting)
+	default:
+		return nil, fmt.Errorf("unsupported hosting provider %q", hosting.Provider)
+	}
+}
+
+// pullRequestProvider returns the hosting provider to look pull requests up
+// with, or "" when lookups are off: the configured provider, or github for
+// github.com remotes when a token is available.
+func pullRequestProvider() string {
+	hosting := config().Hosting
+	switch hosting.Provider {
+	case "":
+		if loc, err := remoteLocation(remoteName); err == nil && loc.host == "github.com" && githubToken(hosting, loc.host) != "" {
+			return "github"
+		}
+		return ""
+	case "none":
+		return ""
+	default:
+		return hosting.Provider
+	}
+}
+
+// openPullRequestsOrWarn is openPullRequests for display purposes, where a
+// failure should not stop the command.
+func openPullRequestsOrWarn() map[string]int {
+	prs, err := openPullRequests()
+	if err != nil {
+		warn("Cannot fetch open pull requests: %s", err)
+	}
+	return prs
+}
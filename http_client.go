@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLSHostConfig adjusts how the certificates of one host are checked, for
+// self-hosted services behind a corporate CA.
+type TLSHostConfig struct {
+	// CAFile is a PEM bundle of certificate authorities trusted for the
+	// host in addition to the system ones.
+	CAFile string `yaml:"ca_file"`
+	// InsecureSkipVerify turns certificate checks off for the host. Only
+	// for testing, and only honored from the global config or git config.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+}
+
+// newHTTPClient returns the client every integration talks HTTP with. It
+// goes through the proxy in HTTPS_PROXY, HTTP_PROXY and NO_PROXY, and
+// checks certificates as configured under tls for the request's host.
+func newHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: hostTransports}
+}
+
+// hostTransports picks a transport per host so that each host gets its own
+// TLS settings while connections are still reused.
+var hostTransports = &hostTransport{transports: make(map[string]http.RoundTripper)}
+
+type hostTransport struct {
+	mu         sync.Mutex
+	transports map[string]http.RoundTripper
+}
+
+func (t *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport, err := t.transport(req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	return transport.RoundTrip(req)
+}
+
+func (t *hostTransport) transport(host string) (http.RoundTripper, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if transport, ok := t.transports[host]; ok {
+		return transport, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if settings, ok := tlsHostConfig(host); ok {
+		tlsConfig, err := settings.tlsConfig()
+		if err != nil {
+			return nil, fmt.Errorf("tls settings for %s: %w", host, err)
+		}
+		transport.TLSClientConfig = tlsConfig
+		if settings.InsecureSkipVerify {
+			warn("Not checking the certificate of %s: insecure_skip_verify is set.", host)
+		}
+	}
+	t.transports[host] = transport
+	return transport, nil
+}
+
+// tlsHostConfig returns the tls settings for host, or those under "*" for
+// hosts not listed. The organization config is fetched before the config is
+// loaded, so it is checked against the system CAs, or SSL_CERT_FILE.
+func tlsHostConfig(host string) (TLSHostConfig, bool) {
+	if loadedConfig == nil {
+		return TLSHostConfig{}, false
+	}
+	settings, ok := loadedConfig.TLS[host]
+	if !ok {
+		settings, ok = loadedConfig.TLS["*"]
+	}
+	return settings, ok
+}
+
+func (c TLSHostConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+	if c.CAFile == "" {
+		return tlsConfig, nil
+	}
+	pem, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates in %s", c.CAFile)
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// absoluteDates shows dates as ISO dates rather than as ages, from
+// --absolute-dates or the absolute_dates config key.
+var absoluteDates bool
+
+// formatWhen renders t as an age such as "3 days ago", or as an ISO date
+// when absoluteDates is set.
+func formatWhen(t time.Time) string {
+	if absoluteDates {
+		return t.Format("2006-01-02")
+	}
+	age := time.Since(t)
+	if age < time.Minute {
+		return "just now"
+	}
+	return humanizeDuration(age) + " ago"
+}
+
+// humanizeDuration renders d in its largest whole unit, such as "5 minutes",
+// "3 days" or "8 months".
+func humanizeDuration(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d < time.Hour:
+		return plural(int(d.Minutes()), "minute")
+	case d < 2*day:
+		return plural(int(d.Hours()), "hour")
+	case d < 14*day:
+		return plural(int(d/day), "day")
+	case d < 60*day:
+		return plural(int(d/(7*day)), "week")
+	case d < 365*day:
+		return plural(int(d/(30*day)), "month")
+	default:
+		return plural(int(d/(365*day)), "year")
+	}
+}
+
+// plural renders n followed by unit, adding an s unless n is 1.
+func plural(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
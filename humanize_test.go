@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHumanizeDuration(t *testing.T) {
+	const day = 24 * time.Hour
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{0, "0 minutes"},
+		{time.Minute, "1 minute"},
+		{5 * time.Minute, "5 minutes"},
+		{time.Hour, "1 hour"},
+		{47 * time.Hour, "47 hours"},
+		{2 * day, "2 days"},
+		{13 * day, "13 days"},
+		{14 * day, "2 weeks"},
+		{59 * day, "8 weeks"},
+		{60 * day, "2 months"},
+		{364 * day, "12 months"},
+		{365 * day, "1 year"},
+		{800 * day, "2 years"},
+	}
+	for _, tt := range tests {
+		if got := humanizeDuration(tt.in); got != tt.want {
+			t.Errorf("humanizeDuration(%s) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var mergeMsgBranch = regexp.MustCompile(`'([^']+)'`)
+
+// branchesInProgress maps branches taking part in an unfinished rebase,
+// merge, bisect, cherry-pick or revert in any worktree to a description of
+// that operation.
+func branchesInProgress() map[string]string {
+	common, err := gitCommonDir()
+	if err != nil {
+		return nil
+	}
+
+	dirs := []string{common}
+	entries, _ := os.ReadDir(filepath.Join(common, "worktrees"))
+	for _, entry := range entries {
+		dirs = append(dirs, filepath.Join(common, "worktrees", entry.Name()))
+	}
+
+	busy := make(map[string]string)
+	for _, dir := range dirs {
+		head := readBranchRef(filepath.Join(dir, "HEAD"))
+
+		for _, name := range []string{"rebase-merge/head-name", "rebase-apply/head-name"} {
+			if branch := readBranchRef(filepath.Join(dir, name)); branch != "" {
+				busy[branch] = "rebase in progress"
+			}
+		}
+
+		if branch := readBranchRef(filepath.Join(dir, "BISECT_START")); branch != "" {
+			busy[branch] = "bisect in progress"
+		}
+
+		if fileExists(filepath.Join(dir, "MERGE_HEAD")) {
+			if head != "" {
+				busy[head] = "merge in progress"
+			}
+			if msg, err := os.ReadFile(filepath.Join(dir, "MERGE_MSG")); err == nil {
+				firstLine, _, _ := strings.Cut(string(msg), "\n")
+				for _, match := range mergeMsgBranch.FindAllStringSubmatch(firstLine, -1) {
+					busy[match[1]] = "merge in progress"
+				}
+			}
+		}
+
+		if head != "" && fileExists(filepath.Join(dir, "CHERRY_PICK_HEAD")) {
+			busy[head] = "cherry-pick in progress"
+		}
+		if head != "" && fileExists(filepath.Join(dir, "REVERT_HEAD")) {
+			busy[head] = "revert in progress"
+		}
+	}
+	return busy
+}
+
+// readBranchRef reads a git state file holding a symbolic ref or branch name
+// and returns the branch name, or "" if the file is missing or detached.
+func readBranchRef(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	ref := strings.TrimSpace(string(data))
+	ref = strings.TrimPrefix(ref, "ref: ")
+	if branch, ok := strings.CutPrefix(ref, "refs/heads/"); ok {
+		return branch
+	}
+	if branchExists(ref) {
+		return ref
+	}
+	return ""
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// filterBusyBranches removes branches involved in an unfinished operation,
+// since deleting them mid-operation breaks it.
+func filterBusyBranches(branches []string) []string {
+	busy := branchesInProgress()
+
+	var filtered []string
+	for _, branch := range branches {
+		if reason, ok := busy[branch]; ok {
+			warn("Branch %s cannot be deleted: %s.", branch, reason)
+		} else {
+			filtered = append(filtered, branch)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runInit walks a new user through setting up completion, the branches to
+// protect and compare against, and a hosting provider, then writes the
+// answers to a config file.
+func runInit(args []string) {
+	if len(args) != 0 {
+		usageError("init")
+	}
+	title("Setting up %s", AppName)
+
+	initCompletion()
+
+	settings := make(map[string]any)
+	inRepo := repoConfigPath() != ""
+	detected := "main"
+	if inRepo {
+		detected = defaultBaseBranch()
+	}
+	base := askDefault("Branch that work is merged into", detected)
+	if base != detected {
+		settings["base"] = base
+	}
+
+	protected := strings.Fields(askDefault("Branch patterns that must never be deleted, separated by spaces", "release/*"))
+	if len(protected) > 0 {
+		settings["protected"] = protected
+	}
+
+	if askYesNo("Show the open GitHub pull request of each branch?", false) {
+		settings["hosting"] = map[string]any{"provider": "github"}
+		if askYesNo("Store a GitHub token in the OS keychain now? Not needed if you use the gh CLI.", false) {
+			account, err := credentialAccount("github", "")
+			if err == nil {
+				authLogin(account)
+			}
+		}
+	}
+
+	path := globalConfigPath()
+	if inRepo && askYesNo("Save these settings for this repository only? Otherwise they apply to all repositories.", false) {
+		path = repoConfigPath()
+	}
+	if path == "" {
+		warn("Cannot find a place for the config file.")
+		exit(1)
+	}
+	if err := writeInitConfig(path, settings); err != nil {
+		warn("Error writing %s: %s", path, err)
+		exit(1)
+	}
+	status("Wrote %s. Run '%s list' to see your branches.", path, AppName)
+}
+
+// initCompletion offers to install the completion script for the user's
+// shell, where that is a matter of writing a file.
+func initCompletion() {
+	shell := filepath.Base(os.Getenv("SHELL"))
+	if completionScripts[shell] == "" {
+		info("Tab completion is available for bash, zsh, fish and elvish; see '%s help generate-completion'.", AppName)
+		return
+	}
+	if !askYesNo("Install tab completion for "+shell+"?", true) {
+		return
+	}
+
+	var path string
+	var after []string
+	switch shell {
+	case "bash", "fish":
+		path = completionFiles(shell)[0]
+	case "zsh":
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, ".zfunc", "_"+AppName)
+		after = completionFixes["zsh"][2:]
+	default:
+		after = completionFixes[shell]
+	}
+	if path != "" {
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err == nil {
+			err = os.WriteFile(path, []byte(completionScript(shell)), 0o644)
+		}
+		if err != nil {
+			warn("Error installing completion: %s", err)
+			return
+		}
+		info("Installed the completion script at %s.", path)
+	}
+	for _, line := range after {
+		info("    %s", strings.ReplaceAll(line, "{{app}}", AppName))
+	}
+	if len(after) > 0 {
+		info("Then check it with '%s completion check'.", AppName)
+	}
+}
+
+// askDefault asks question, returning def when the answer is empty.
+func askDefault(question, def string) string {
+	prompt := question + ":"
+	if def != "" {
+		prompt = question + " [" + def + "]:"
+	}
+	answer, err := ask(prompt)
+	if err != nil {
+		exit(1)
+	}
+	if answer == "" {
+		return def
+	}
+	return answer
+}
+
+// askYesNo asks a yes or no question, returning def when the answer is
+// empty.
+func askYesNo(question string, def bool) bool {
+	hint := " [y/N]"
+	if def {
+		hint = " [Y/n]"
+	}
+	answer, err := ask(question + hint)
+	if err != nil {
+		exit(1)
+	}
+	switch strings.ToLower(answer) {
+	case "":
+		return def
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// writeInitConfig sets settings in the config file at path, keeping the
+// other settings of an existing file.
+func writeInitConfig(path string, settings map[string]any) error {
+	existing := make(map[string]any)
+	if data, err := os.ReadFile(path); err == nil {
+		if err := yaml.Unmarshal(data, &existing); err != nil {
+			return err
+		}
+		if existing == nil {
+			existing = make(map[string]any)
+		}
+	}
+	for key, value := range settings {
+		if nested, ok := value.(map[string]any); ok {
+			if old, ok := existing[key].(map[string]any); ok {
+				for k, v := range nested {
+					old[k] = v
+				}
+				continue
+			}
+		}
+		existing[key] = value
+	}
+
+	data, err := yaml.Marshal(existing)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/fatih/color"
+)
+
+// startJSONOutput sends the messages meant for people to stderr, so that
+// stdout carries only the JSON. Every printer, plain or not, and the output
+// of the commands run for the user go through color.Output.
+func startJSONOutput() {
+	color.Output = os.Stderr
+}
+
+// writeJSON prints v as indented JSON on stdout.
+func writeJSON(v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		warn("Error encoding JSON: %s", err)
+		exit(1)
+	}
+	os.Stdout.Write(append(data, '\n'))
+}
+
+func listBranchesAsJSON(sel selection) {
+	startJSONOutput()
+	records, err := selectedBranchRecords(sel)
+	if err != nil {
+		warn("Error listing branches: %s", err)
+		exit(1)
+	}
+	writeJSON(records)
+}
+
+// deleteErrors holds git's reason for each branch a deletion failed on.
+var deleteErrors = make(map[string]string)
+
+// deletionReport is what --json prints for a deleting command: the branches
+// it planned to delete, those it left out, and what happened to each.
+type deletionReport struct {
+	DryRun  bool             `json:"dry_run"`
+	Planned []branchRecord   `json:"planned"`
+	Skipped []skippedRecord  `json:"skipped"`
+	Results []deletionResult `json:"results"`
+}
+
+type skippedRecord struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+type deletionResult struct {
+	Name    string `json:"name"`
+	Deleted bool   `json:"deleted"`
+	Error   string `json:"error,omitempty"`
+}
+
+func newDeletionReport(planned []string, skipped []skippedBranch) *deletionReport {
+	report := &deletionReport{
+		DryRun:  dryRun,
+		Planned: []branchRecord{},
+		Skipped: []skippedRecord{},
+		Results: []deletionResult{},
+	}
+	infos, err := loadBranchInfos(planned)
+	if err != nil {
+		warn("Error reading branch details: %s", err)
+		exit(1)
+	}
+	labels := branchLabels()
+	for _, branch := range planned {
+		report.Planned = append(report.Planned, newBranchRecord(infos[branch], labels[branch]))
+	}
+	for _, s := range skipped {
+		report.Skipped = append(report.Skipped, skippedRecord{s.name, s.reason})
+	}
+	return report
+}
+
+// addResults records whether each of attempted is gone now.
+func (r *deletionReport) addResults(attempted []string) {
+	tips, _ := branchTips()
+	for _, branch := range attempted {
+		_, remains := tips[branch]
+		result := deletionResult{Name: branch, Deleted: !remains}
+		if remains {
+			result.Error = deleteErrors[branch]
+			if result.Error == "" {
+				result.Error = "not deleted"
+			}
+		}
+		r.Results = append(r.Results, result)
+	}
+}
+
+func (r *deletionReport) write() {
+	writeJSON(r)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestDeleteJSONKeepsStdoutJSON(t *testing.T) {
+	newTestRepo(t, "tmp/a", "tmp/b", "keep")
+
+	// Answer the confirmation from stdin rather than the terminal.
+	savedStdin, savedTTYOpened := stdin, ttyOpened
+	stdin, ttyOpened = bufio.NewReader(strings.NewReader("yes\n")), true
+	savedPrinters := []func(string, ...interface{}){title, status, info, warn}
+	defer func() {
+		stdin, ttyOpened = savedStdin, savedTTYOpened
+		title, status, info, warn = savedPrinters[0], savedPrinters[1], savedPrinters[2], savedPrinters[3]
+		plainOutput, color.NoColor = false, false
+	}()
+	setPlainOutput()
+
+	out := captureStdout(t, func() {
+		runDelete([]string{"tmp/*", "--json", "--force"}, false)
+	})
+
+	var report struct {
+		Planned []struct {
+			Name string `json:"name"`
+		} `json:"planned"`
+		Results []struct {
+			Name    string `json:"name"`
+			Deleted bool   `json:"deleted"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("stdout is not JSON: %s\n%s", err, out)
+	}
+	if len(report.Planned) != 2 || len(report.Results) != 2 {
+		t.Errorf("planned %d and reported %d branches, want 2 and 2:\n%s", len(report.Planned), len(report.Results), out)
+	}
+	for _, result := range report.Results {
+		if !result.Deleted {
+			t.Errorf("%s was not deleted:\n%s", result.Name, out)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// parseKeepLast extracts --last N and --by-checkout from args. last is -1
+// when --last is not given.
+func parseKeepLast(args []string) (last int, byCheckout bool, rest []string) {
+	value, ok, rest := extractOption(args, "--last")
+	byCheckout, rest = extractFlag(rest, "--by-checkout")
+	if !ok {
+		return -1, byCheckout, rest
+	}
+	last, err := strconv.Atoi(value)
+	if err != nil || last < 0 {
+		fatalf("Invalid --last %q: must be a number", value)
+	}
+	return last, byCheckout, rest
+}
+
+// newestBranches returns the n branches with the most recent last commit,
+// or the most recent checkout if byCheckout is set. The current branch and
+// protected branches are kept anyway, so they are not counted.
+func newestBranches(n int, byCheckout bool) []string {
+	branches, current, err := listBranches()
+	if err != nil {
+		warn("Error listing branches: %s", err)
+		exit(1)
+	}
+
+	var when map[string]time.Time
+	if byCheckout {
+		stats, _, err := readCheckoutStats()
+		if err != nil {
+			warn("Error reading the reflog: %s", err)
+			exit(1)
+		}
+		when = make(map[string]time.Time, len(stats))
+		for branch, s := range stats {
+			when[branch] = s.last
+		}
+	} else if when, err = branchCommitDates(); err != nil {
+		warn("Error listing branches: %s", err)
+		exit(1)
+	}
+
+	patterns := protectedPatterns()
+	var candidates []string
+	for _, branch := range branches {
+		if branch != current && !isProtected(branch, patterns) {
+			candidates = append(candidates, branch)
+		}
+	}
+	return newestFirst(candidates, when, n)
+}
+
+// newestFirst returns the n branches with the latest times in when, keeping
+// the order of branches among equal times. Branches without a time, such as
+// ones never checked out, come last.
+func newestFirst(branches []string, when map[string]time.Time, n int) []string {
+	sorted := append([]string(nil), branches...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return when[sorted[i]].After(when[sorted[j]])
+	})
+	return sorted[:min(n, len(sorted))]
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewestFirst(t *testing.T) {
+	when := map[string]time.Time{
+		"old":    time.Unix(100, 0),
+		"newest": time.Unix(300, 0),
+		"middle": time.Unix(200, 0),
+		"tie":    time.Unix(200, 0),
+	}
+	branches := []string{"never", "old", "middle", "newest", "tie"}
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{0, []string{}},
+		{1, []string{"newest"}},
+		{3, []string{"newest", "middle", "tie"}},
+		{10, []string{"newest", "middle", "tie", "old", "never"}},
+	}
+	for _, tt := range tests {
+		if got := newestFirst(branches, when, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("newestFirst(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+// The times must be those of the checkouts, not of the commits checked out:
+// a branch with an old commit that was checked out last is the newest.
+func TestNewestFirstByCheckout(t *testing.T) {
+	stats, _ := parseCheckoutStats([]string{
+		"HEAD@{300}\x00checkout: moving from recent-work to old-commit",
+		"HEAD@{200}\x00checkout: moving from main to recent-work",
+		"HEAD@{100}\x00checkout: moving from old-commit to main",
+	})
+	when := make(map[string]time.Time)
+	for branch, s := range stats {
+		when[branch] = s.last
+	}
+	got := newestFirst([]string{"main", "recent-work", "old-commit"}, when, 2)
+	if want := []string{"old-commit", "recent-work"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("newestFirst by checkout = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// labelKey is the variable in a branch's git config section that holds its
+// labels, one value per label. git renames and removes the section along with
+// the branch, so labels follow renames and disappear on deletion.
+var labelKey = AppName + "label"
+
+// runLabel shows, adds or removes the free-form labels of a branch.
+func runLabel(args []string) {
+	remove, args := extractFlag(args, "--remove")
+	clear, args := extractFlag(args, "--clear")
+	if len(args) == 0 {
+		if remove || clear {
+			usageError("label")
+		}
+		showAllLabels()
+		return
+	}
+
+	branch, err := resolveBranchArg(args[0])
+	if err != nil {
+		warn(err.Error())
+		exit(1)
+	}
+	key := "branch." + branch + "." + labelKey
+	text := strings.Join(args[1:], " ")
+
+	switch {
+	case clear:
+		if text != "" {
+			usageError("label")
+		}
+		_ = gitQuiet("config", "--unset-all", key)
+		status("Removed the labels of %s.", branch)
+	case remove:
+		if text == "" {
+			usageError("label")
+		}
+		if err := gitQuiet("config", "--unset-all", key, "^"+regexp.QuoteMeta(text)+"$"); err != nil {
+			warn("%s has no label %q.", branch, text)
+			exit(1)
+		}
+		status("Removed label %q from %s.", text, branch)
+	case text == "":
+		labels := branchLabelsOf(branch)
+		if len(labels) == 0 {
+			status("%s has no labels.", branch)
+			return
+		}
+		title("Labels of %s", branch)
+		for _, label := range labels {
+			info("%s", label)
+		}
+	default:
+		if contains(branchLabelsOf(branch), text) {
+			status("%s is already labeled %q.", branch, text)
+			return
+		}
+		if err := gitRun("config", "--add", key, text); err != nil {
+			exit(1)
+		}
+		status("Labeled %s %q.", branch, text)
+	}
+}
+
+// showAllLabels lists every labeled branch with its labels.
+func showAllLabels() {
+	labels := branchLabels()
+	if len(labels) == 0 {
+		status("No branches are labeled. Add a label with '%s label <branch> <text>'.", AppName)
+		return
+	}
+
+	var branches []string
+	for branch := range labels {
+		branches = append(branches, branch)
+	}
+	sort.Strings(branches)
+	title("Labeled branches")
+	for _, branch := range branches {
+		info("%s%s", branch, labelSuffix(labels[branch]))
+	}
+}
+
+// branchLabels maps each labeled branch to its labels.
+func branchLabels() map[string][]string {
+	labels := make(map[string][]string)
+	pattern := `^branch\..*\.` + regexp.QuoteMeta(labelKey) + `$`
+	// git config exits with an error when nothing matches.
+	lines, _ := gitLines("config", "--get-regexp", pattern)
+	for _, line := range lines {
+		key, value, _ := strings.Cut(line, " ")
+		branch := strings.TrimSuffix(strings.TrimPrefix(key, "branch."), "."+strings.ToLower(labelKey))
+		labels[branch] = append(labels[branch], value)
+	}
+	return labels
+}
+
+// branchLabelsOf returns the labels of branch.
+func branchLabelsOf(branch string) []string {
+	labels, _ := gitLines("config", "--get-all", "branch."+branch+"."+labelKey)
+	return labels
+}
+
+// hasAnyLabel reports whether one of labels matches one of patterns,
+// ignoring case.
+func hasAnyLabel(labels []string, patterns []string) bool {
+	for _, label := range labels {
+		for _, pattern := range patterns {
+			if matchesPatternCase(label, pattern, true) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// labelSuffix renders labels for appending to a branch name in listings.
+func labelSuffix(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	if plainOutput {
+		return ", labels: " + strings.Join(labels, "; ")
+	}
+	return fmt.Sprintf("  [%s]", strings.Join(labels, "] ["))
+}
+
+// listBranchesByLabel prints branches grouped under each of their labels,
+// so a branch with two labels is in two groups, followed by the branches
+// with none.
+func listBranchesByLabel(branches []string) {
+	infos, err := loadBranchInfos(branches)
+	if err != nil {
+		warn("Error reading branch details: %s", err)
+		exit(1)
+	}
+	labels := branchLabels()
+
+	groups := make(map[string][]branchInfo)
+	var unlabeled []branchInfo
+	for _, branch := range branches {
+		b := infos[branch]
+		if len(labels[branch]) == 0 {
+			unlabeled = append(unlabeled, b)
+		}
+		for _, label := range labels[branch] {
+			groups[label] = append(groups[label], b)
+		}
+	}
+
+	var names []string
+	for label := range groups {
+		names = append(names, label)
+	}
+	sort.Slice(names, func(i, j int) bool { return strings.ToLower(names[i]) < strings.ToLower(names[j]) })
+
+	for _, label := range names {
+		showLabelGroup(label, groups[label])
+	}
+	showLabelGroup("(no label)", unlabeled)
+}
+
+func showLabelGroup(heading string, group []branchInfo) {
+	if len(group) == 0 {
+		return
+	}
+	sort.Slice(group, func(i, j int) bool { return group[i].Date.Before(group[j].Date) })
+	title("%s: %s", heading, branchCount(len(group)))
+	for _, b := range group {
+		info("    %s (last commit %s, %s)", b.Name, formatWhen(b.Date), b.mergedLabel())
+	}
+}
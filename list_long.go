@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// listBranchesLong prints sorted branches as a table with their tip, last
+// commit, merge state and upstream, each with its index in indexes.
+func listBranchesLong(branches []string, indexes map[string]int) {
+	infos, err := loadBranchInfos(branches)
+	if err != nil {
+		warn("Error reading branch details: %s", err)
+		exit(1)
+	}
+
+	labels := branchLabels()
+	t := table{}
+	var prefixes []string
+	for _, branch := range branches {
+		i := indexes[branch]
+		b := infos[branch]
+		upstream := b.Upstream
+		if upstream != "" && b.Track != "" {
+			upstream = fmt.Sprintf("%s [%s]", upstream, b.Track)
+		}
+
+		if plainOutput {
+			line := fmt.Sprintf("branch %d of %d: %s, commit %s, last commit %s by %s, %s",
+				i, len(indexes), branch, shortSHA(b.SHA), formatWhen(b.Date), b.Author, b.mergedLabel())
+			if upstream != "" {
+				line += ", tracking " + upstream
+			}
+			info("%s", line+labelSuffix(labels[branch]))
+			continue
+		}
+
+		marker := " "
+		if b.Current {
+			marker = "*"
+		}
+		prefixes = append(prefixes, fmt.Sprintf("%2d. %s ", i, marker))
+		t.addRow(branch, shortSHA(b.SHA), formatWhen(b.Date), b.Author, b.mergedLabel(), upstream, strings.TrimSpace(labelSuffix(labels[branch])))
+	}
+
+	for i, line := range t.lines() {
+		info("%s%s", prefixes[i], line)
+	}
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// listBranchesVV prints the `git branch -vv` lines of the given sorted
+// branches unchanged, each prefixed with its index in indexes.
+func listBranchesVV(branches []string, indexes map[string]int) {
+	output, err := gitOutput("branch", "-vv", "--color=never")
+	if err != nil {
+		warn("Error listing branches: %s", err)
+		exit(1)
+	}
+
+	lines := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		// Lines start with a two-character marker column, then the name.
+		if len(line) < 3 {
+			continue
+		}
+		name, _, _ := strings.Cut(line[2:], " ")
+		lines[name] = line
+	}
+
+	for _, branch := range branches {
+		if line, ok := lines[branch]; ok {
+			fmt.Printf("%2d. %s\n", indexes[branch], line)
+		}
+	}
+}
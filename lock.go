@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// lockHolder is written to the lock file to show who holds it.
+type lockHolder struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	User    string    `json:"user"`
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+}
+
+var lockPath string
+
+// unreadableLockTimeout is how old a lock file that cannot be read must be
+// before it is taken to be left over from a run that died.
+const unreadableLockTimeout = time.Minute
+
+func lockFilePath() (string, error) {
+	common, err := gitCommonDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(common, AppName+".lock"), nil
+}
+
+// lockRepository takes the repository's operation lock or exits, showing
+// who holds it. The lock is released by unlockRepository, or on interrupt.
+func lockRepository(args []string) {
+	path, err := lockFilePath()
+	if err != nil {
+		// Outside a repository there is nothing to protect, and the
+		// command reports the problem itself.
+		return
+	}
+
+	me := lockHolder{PID: os.Getpid(), User: currentUser(), Command: AppName + " " + strings.Join(args, " "), Started: time.Now()}
+	me.Host, _ = os.Hostname()
+	data, err := json.Marshal(me)
+	if err != nil {
+		return
+	}
+
+	// The lock is written in full to a file of its own and then linked into
+	// place, so that nobody ever sees a lock file without its holder.
+	tmp := fmt.Sprintf("%s.%d", path, me.PID)
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		warn("Cannot write lock file %s: %s", tmp, err)
+		os.Exit(1)
+	}
+	// os.Exit skips deferred calls, so giving up removes the file itself.
+	giveUp := func() {
+		os.Remove(tmp)
+		os.Exit(1)
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		err := os.Link(tmp, path)
+		if err == nil {
+			os.Remove(tmp)
+			lockPath = path
+			releaseOnInterrupt()
+			return
+		}
+		if !errors.Is(err, os.ErrExist) {
+			warn("Cannot create lock file %s: %s", path, err)
+			giveUp()
+		}
+
+		holder, stale, err := readLockHolder(path)
+		if errors.Is(err, os.ErrNotExist) {
+			// Released since the link failed.
+			continue
+		}
+		if err != nil {
+			// A lock that cannot be read may be from a version that wrote
+			// it in place and is still writing, so it only counts as
+			// stale once it is old.
+			if stat, statErr := os.Stat(path); statErr == nil && time.Since(stat.ModTime()) < unreadableLockTimeout {
+				warn("The lock file %s cannot be read: %s", path, err)
+				warn("If no other %s run is working on this repository, remove it.", AppName)
+				giveUp()
+			}
+		} else if holder.isAlive() {
+			warn("Another %s run is working on this repository:", AppName)
+			warn("    %s", holder.Command)
+			warn("    started %s by %s on %s, process %d", holder.Started.Format("2006-01-02 15:04:05"), holder.User, holder.Host, holder.PID)
+			warn("Wait for it to finish. If it is no longer running, remove %s.", path)
+			giveUp()
+		}
+
+		// The holder has exited without releasing the lock. Removing it
+		// and linking again could remove the lock of a run that got there
+		// first, so ours is renamed over it in one step, and only if it is
+		// still the stale one.
+		if current, err := os.ReadFile(path); err != nil || !bytes.Equal(current, stale) {
+			continue
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			warn("Cannot replace the stale lock file %s: %s", path, err)
+			giveUp()
+		}
+		// A run that took over the same stale lock at the same time may
+		// have renamed its own over ours; the lock left in place wins.
+		if current, err := os.ReadFile(path); err != nil || !bytes.Equal(current, data) {
+			warn("Another %s run took over the stale lock %s.", AppName, path)
+			os.Exit(1)
+		}
+		lockPath = path
+		releaseOnInterrupt()
+		return
+	}
+	warn("Cannot take the lock %s.", path)
+	giveUp()
+}
+
+// unlockRepository releases the lock taken by lockRepository, if any.
+func unlockRepository() {
+	if lockPath != "" {
+		os.Remove(lockPath)
+		lockPath = ""
+	}
+}
+
+// exit releases the repository lock and exits with code. Commands exit
+// through it, or through fatal and fatalf, since os.Exit and log.Fatal
+// skip the unlock deferred in main.
+func exit(code int) {
+	unlockRepository()
+	os.Exit(code)
+}
+
+// fatal is log.Fatal, releasing the repository lock first.
+func fatal(v ...any) {
+	log.Print(v...)
+	exit(1)
+}
+
+// fatalf is log.Fatalf, releasing the repository lock first.
+func fatalf(format string, v ...any) {
+	log.Printf(format, v...)
+	exit(1)
+}
+
+func releaseOnInterrupt() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		unlockRepository()
+		os.Exit(130)
+	}()
+}
+
+// readLockHolder returns the holder of the lock file at path along with the
+// file's contents.
+func readLockHolder(path string) (lockHolder, []byte, error) {
+	var holder lockHolder
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return holder, nil, err
+	}
+	if err := json.Unmarshal(data, &holder); err != nil {
+		return holder, data, fmt.Errorf("invalid lock file: %w", err)
+	}
+	return holder, data, nil
+}
+
+// isAlive reports whether the lock holder is still running. A holder on
+// another host cannot be checked and is assumed to be running.
+func (h lockHolder) isAlive() bool {
+	if host, _ := os.Hostname(); host != h.Host {
+		return true
+	}
+	return processAlive(h.PID)
+}
+
+func currentUser() string {
+	for _, name := range []string{"USER", "USERNAME"} {
+		if user := os.Getenv(name); user != "" {
+			return user
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestLockRepositoryTakesOverStaleLock(t *testing.T) {
+	newTestRepo(t)
+	path, err := lockFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A process that has exited stands in for a run that died holding the lock.
+	dead := exec.Command("git", "--version")
+	if err := dead.Run(); err != nil {
+		t.Fatal(err)
+	}
+	stale := lockHolder{PID: dead.Process.Pid, Command: AppName + " delete", Started: time.Now()}
+	stale.Host, _ = os.Hostname()
+	data, _ := json.Marshal(stale)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	lockRepository([]string{"test"})
+	defer unlockRepository()
+	holder, _, err := readLockHolder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder.PID != os.Getpid() {
+		t.Errorf("lock held by process %d, want %d", holder.PID, os.Getpid())
+	}
+	if _, err := os.Stat(fmt.Sprintf("%s.%d", path, os.Getpid())); !os.IsNotExist(err) {
+		t.Errorf("temporary lock file left behind: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+// maxLostSubjects is how many commit subjects are shown per branch when
+// listing commits that a force delete would lose.
+const maxLostSubjects = 3
+
+// uniqueCommits returns the "<sha> <subject>" lines of commits reachable from
+// branch but from no ref other than the branches in deleting.
+func uniqueCommits(branch string, deleting []string) ([]string, error) {
+	args := []string{"log", "--format=%h %s", "refs/heads/" + branch, "--not"}
+	for _, b := range deleting {
+		args = append(args, "--exclude=refs/heads/"+b)
+	}
+	args = append(args, "--all", "--")
+	return gitLines(args...)
+}
+
+// showLostCommits prints the commits that would become unreachable if the
+// branches in deleting were removed, for each of branches.
+func showLostCommits(branches []string, deleting []string) {
+	shown := false
+	for _, branch := range branches {
+		commits, err := uniqueCommits(branch, deleting)
+		if err != nil || len(commits) == 0 {
+			continue
+		}
+
+		if !shown {
+			title("Commits that exist only on these branches and would be lost:")
+			shown = true
+		}
+
+		commitStr := "commits"
+		if len(commits) == 1 {
+			commitStr = "commit"
+		}
+		warn("%s: %d %s", branch, len(commits), commitStr)
+		for i, commit := range commits {
+			if i == maxLostSubjects {
+				info("    ... and %d more", len(commits)-maxLostSubjects)
+				break
+			}
+			info("    %s", commit)
+		}
+	}
+
+	if !shown {
+		status("No commits would be lost.")
+	}
+}
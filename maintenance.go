@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MaintenanceConfig controls the housekeeping run after bulk deletions.
+type MaintenanceConfig struct {
+	// After is what to run once enough branches are deleted: gc for
+	// `git gc --auto`, maintenance for `git maintenance run`, or none.
+	After string `yaml:"after"`
+	// Threshold is how many branches a single run must delete before
+	// maintenance starts.
+	Threshold int `yaml:"threshold"`
+	// PackRefs is how many refs a single run must create or delete before
+	// `git pack-refs --all` is run. Zero turns it off.
+	PackRefs int `yaml:"pack_refs"`
+}
+
+func runMaintenanceCommand(args []string) {
+	if len(args) != 1 || args[0] != "pack-refs" {
+		usageError("maintenance")
+	}
+	if !packRefs() {
+		exit(1)
+	}
+}
+
+// objectStats is the number of objects in the repository and the disk
+// space they take, as reported by `git count-objects -v`.
+type objectStats struct {
+	count   int
+	sizeKiB int
+}
+
+func countObjects() (objectStats, error) {
+	var stats objectStats
+	lines, err := gitLines("count-objects", "-v")
+	if err != nil {
+		return stats, err
+	}
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		var field *int
+		switch key {
+		case "count", "in-pack":
+			field = &stats.count
+		case "size", "size-pack":
+			field = &stats.sizeKiB
+		default:
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return stats, err
+		}
+		*field += n
+	}
+	return stats, nil
+}
+
+// runMaintenance runs the configured housekeeping once deleted reaches the
+// threshold, so the space held by the deleted branches is reclaimed, and
+// reports the object count and size before and after.
+func runMaintenance(deleted int, before objectStats) {
+	cfg := config().Maintenance
+	if deleted == 0 || deleted < cfg.Threshold {
+		return
+	}
+
+	var args []string
+	switch cfg.After {
+	case "gc":
+		args = []string{"gc", "--auto"}
+	case "maintenance":
+		args = []string{"maintenance", "run"}
+	case "none", "":
+		return
+	default:
+		warn("Unknown maintenance setting %q. Use gc, maintenance or none.", cfg.After)
+		return
+	}
+
+	title("Running git %s", strings.Join(args, " "))
+	if err := gitRun(args...); err != nil {
+		warn("Error running git %s.", strings.Join(args, " "))
+		return
+	}
+
+	after, err := countObjects()
+	if err != nil {
+		return
+	}
+	status("Objects: %d before, %d after (%d KiB to %d KiB).", before.count, after.count, before.sizeKiB, after.sizeKiB)
+}
+
+// packRefsIfMany packs loose refs once a run has created or deleted at least
+// the configured number of them, so .git/refs does not fill up with
+// thousands of small files.
+func packRefsIfMany(changed int) {
+	limit := config().Maintenance.PackRefs
+	if limit <= 0 || changed < limit {
+		return
+	}
+	packRefs()
+}
+
+func packRefs() bool {
+	title("Packing refs")
+	if err := gitRun("pack-refs", "--all"); err != nil {
+		warn("Error packing refs.")
+		return false
+	}
+	status("Refs packed.")
+	return true
+}
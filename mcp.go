@@ -0,0 +1,461 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// mcpProtocolVersion is the Model Context Protocol revision the server
+// speaks when the client does not ask for another.
+const mcpProtocolVersion = "2024-11-05"
+
+// JSON-RPC error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// mcpTool is a tool the server offers, with the JSON schema of its
+// arguments and the function that runs it.
+type mcpTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+	call        func(args map[string]interface{}) (interface{}, error)
+}
+
+// selectionArgument is a tool argument that selects branches, passed on as
+// the selection flag of the same name.
+type selectionArgument struct {
+	name        string
+	boolean     bool
+	description string
+}
+
+// selectionArguments mirror the selection flags, other than the pattern,
+// which is an argument of its own.
+var selectionArguments = []selectionArgument{
+	{"ignore_case", true, "match the pattern without regard to case"},
+	{"invert", true, "select the branches that do not match the pattern"},
+	{"regex", false, "regular expression the whole branch name must match"},
+	{"merged", true, "only branches merged into a base branch"},
+	{"stale", true, "only branches whose last commit is over 90 days old"},
+	{"older_than", false, "only branches whose last commit is older than this, e.g. 30d"},
+	{"newer_than", false, "only branches whose last commit is newer than this, e.g. 7d"},
+	{"author", false, "only branches whose last commit is by this author"},
+	{"gone", true, "only branches whose upstream was deleted"},
+	{"mine", true, "only branches whose last commit is yours"},
+	{"label", false, "only branches with a label matching this"},
+	{"where", false, "expression such as 'age > 30d'"},
+}
+
+// selectionSchema describes the tool arguments that select branches.
+var selectionSchema = func() map[string]interface{} {
+	schema := map[string]interface{}{
+		"pattern": map[string]interface{}{"type": "string", "description": "glob pattern the branch names must match"},
+	}
+	for _, arg := range selectionArguments {
+		kind := "string"
+		if arg.boolean {
+			kind = "boolean"
+		}
+		schema[arg.name] = map[string]interface{}{"type": kind, "description": arg.description}
+	}
+	return schema
+}()
+
+func mcpTools() []mcpTool {
+	return []mcpTool{
+		{
+			Name:        "list_branches",
+			Description: "List the local branches with their tip, upstream, last commit and whether they are merged.",
+			InputSchema: objectSchema(selectionSchema),
+			call:        mcpListBranches,
+		},
+		{
+			Name:        "stale_branches",
+			Description: "List the branches that have gone without commits for longer than their max-age policy or older_than, oldest first.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"pattern":    selectionSchema["pattern"],
+				"older_than": map[string]interface{}{"type": "string", "description": "age for branches without a max-age policy, 90d by default"},
+			}),
+			call: mcpStaleBranches,
+		},
+		{
+			Name: "plan_deletion",
+			Description: "Plan the deletion of the selected branches, leaving out the current and protected ones. Nothing is deleted: " +
+				"write the plan to a file with out and have the user review it and run '" + AppName + " apply <file>'.",
+			InputSchema: objectSchema(withProperties(selectionSchema, map[string]interface{}{
+				"force": map[string]interface{}{"type": "boolean", "description": "plan a forced deletion of unmerged branches"},
+				"out":   map[string]interface{}{"type": "string", "description": "name of a new file in the repository's " + AppName + "-plans directory to write the plan to"},
+			})),
+			call: mcpPlanDeletion,
+		},
+	}
+}
+
+// runMCP serves the tools over stdio as a Model Context Protocol server, one
+// JSON-RPC message per line, until stdin is closed.
+func runMCP(args []string) {
+	if len(args) != 0 {
+		usageError("mcp")
+	}
+	// stdout carries the protocol, so messages go to stderr.
+	color.Output = os.Stderr
+	color.NoColor = true
+
+	if err := serveMCP(os.Stdin, os.Stdout); err != nil {
+		warn("Error serving MCP: %s", err)
+		exit(1)
+	}
+}
+
+func serveMCP(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	encoder := json.NewEncoder(w)
+	tools := mcpTools()
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if response, ok := handleMCPMessage(line, tools); ok {
+				if err := encoder.Encode(response); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handleMCPMessage answers one message. Notifications, which have no id,
+// get no response.
+func handleMCPMessage(line []byte, tools []mcpTool) (rpcResponse, bool) {
+	var req rpcRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		if len(bytes.TrimSpace(line)) == 0 {
+			return rpcResponse{}, false
+		}
+		return rpcErrorResponse(nil, rpcParseError, "invalid JSON: "+err.Error()), true
+	}
+	if req.Method == "" {
+		return rpcErrorResponse(req.ID, rpcInvalidRequest, "missing method"), true
+	}
+	if len(req.ID) == 0 {
+		return rpcResponse{}, false
+	}
+
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(req.Params, &params)
+		version := params.ProtocolVersion
+		if version == "" {
+			version = mcpProtocolVersion
+		}
+		return rpcResult(req.ID, map[string]interface{}{
+			"protocolVersion": version,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]interface{}{"name": AppName, "version": buildVersion()},
+		}), true
+	case "ping":
+		return rpcResult(req.ID, map[string]interface{}{}), true
+	case "tools/list":
+		return rpcResult(req.ID, map[string]interface{}{"tools": tools}), true
+	case "tools/call":
+		var params struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return rpcErrorResponse(req.ID, rpcInvalidParams, err.Error()), true
+		}
+		for _, tool := range tools {
+			if tool.Name == params.Name {
+				return rpcResult(req.ID, callMCPTool(tool, params.Arguments)), true
+			}
+		}
+		return rpcErrorResponse(req.ID, rpcInvalidParams, "unknown tool "+params.Name), true
+	}
+	return rpcErrorResponse(req.ID, rpcMethodNotFound, "unknown method "+req.Method), true
+}
+
+// callMCPTool runs tool and wraps its result, or its error, as tool output.
+func callMCPTool(tool mcpTool, args map[string]interface{}) map[string]interface{} {
+	result, err := tool.call(args)
+	if err != nil {
+		return mcpToolError(err)
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcpToolError(err)
+	}
+	return map[string]interface{}{
+		"content": []map[string]string{{"type": "text", "text": string(data)}},
+	}
+}
+
+// mcpToolError reports err as the output of a failed tool call.
+func mcpToolError(err error) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]string{{"type": "text", "text": err.Error()}},
+		"isError": true,
+	}
+}
+
+func rpcResult(id json.RawMessage, result interface{}) rpcResponse {
+	return rpcResponse{JSONRPC: "2.0", ID: id, Result: result}
+}
+
+func rpcErrorResponse(id json.RawMessage, code int, message string) rpcResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return rpcResponse{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}}
+}
+
+// branchRecord is a branch as the structured interfaces report it.
+type branchRecord struct {
+	Name       string    `json:"name"`
+	SHA        string    `json:"sha"`
+	Upstream   string    `json:"upstream,omitempty"`
+	Track      string    `json:"track,omitempty"`
+	Author     string    `json:"author"`
+	Email      string    `json:"email"`
+	LastCommit time.Time `json:"last_commit"`
+	Current    bool      `json:"current"`
+	Merged     bool      `json:"merged"`
+	Labels     []string  `json:"labels,omitempty"`
+}
+
+func newBranchRecord(b branchInfo, labels []string) branchRecord {
+	return branchRecord{
+		Name:       b.Name,
+		SHA:        b.SHA,
+		Upstream:   b.Upstream,
+		Track:      b.Track,
+		Author:     b.Author,
+		Email:      b.Email,
+		LastCommit: b.Date,
+		Current:    b.Current,
+		Merged:     b.Merged,
+		Labels:     labels,
+	}
+}
+
+func mcpListBranches(args map[string]interface{}) (interface{}, error) {
+	sel, err := selectionFromArguments(args)
+	if err != nil {
+		return nil, err
+	}
+	return selectedBranchRecords(sel)
+}
+
+// selectedBranchRecords returns the branches sel picks, in list order.
+func selectedBranchRecords(sel selection) ([]branchRecord, error) {
+	branches, _, err := listBranches()
+	if err == nil {
+		branches, err = sel.filter(branches)
+	}
+	if err != nil {
+		return nil, err
+	}
+	infos, err := loadBranchInfos(branches)
+	if err != nil {
+		return nil, err
+	}
+
+	sortBranches(branches)
+	labels := branchLabels()
+	records := []branchRecord{}
+	for _, branch := range branches {
+		records = append(records, newBranchRecord(infos[branch], labels[branch]))
+	}
+	return records, nil
+}
+
+// staleRecord is a branch past its age limit.
+type staleRecord struct {
+	branchRecord
+	AgeDays   int    `json:"age_days"`
+	LimitDays int    `json:"limit_days"`
+	Policy    string `json:"policy,omitempty"`
+}
+
+func mcpStaleBranches(args map[string]interface{}) (interface{}, error) {
+	limit := staleAfter
+	if value, ok := args["older_than"].(string); ok && value != "" {
+		d, err := parseAge(value)
+		if err != nil {
+			return nil, err
+		}
+		limit = d
+	}
+	sel, err := selectionFromArguments(map[string]interface{}{"pattern": args["pattern"]})
+	if err != nil {
+		return nil, err
+	}
+
+	branches, _, err := listBranches()
+	if err == nil {
+		branches, err = sel.filter(branches)
+	}
+	if err != nil {
+		return nil, err
+	}
+	infos, err := loadBranchInfos(branches)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := branchLabels()
+	records := []staleRecord{}
+	for _, branch := range branches {
+		b := infos[branch]
+		branchLimit, policy, ok := maxAgeFor(branch)
+		if !ok {
+			branchLimit = limit
+		}
+		age := time.Since(b.Date)
+		if age < branchLimit {
+			continue
+		}
+		records = append(records, staleRecord{
+			branchRecord: newBranchRecord(b, labels[branch]),
+			AgeDays:      int(age.Hours() / 24),
+			LimitDays:    int(branchLimit.Hours() / 24),
+			Policy:       policy,
+		})
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].LastCommit.Before(records[j].LastCommit)
+	})
+	return records, nil
+}
+
+func mcpPlanDeletion(args map[string]interface{}) (interface{}, error) {
+	sel, err := selectionFromArguments(args)
+	if err != nil {
+		return nil, err
+	}
+	if sel.pattern == "" && !sel.hasFilters() {
+		return nil, errors.New("give a pattern or at least one filter, so that not every branch is planned for deletion")
+	}
+	force, _ := args["force"].(bool)
+	plan, err := newDeletionPlan(sel, force)
+	if err != nil {
+		return nil, err
+	}
+
+	out, _ := args["out"].(string)
+	if out == "" {
+		return plan, nil
+	}
+	if out == "." || out == ".." || filepath.Base(out) != out {
+		return nil, fmt.Errorf("out must be a file name, not a path: %s", out)
+	}
+	dir, err := plansDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, out)
+	if err := writeNewPlan(plan, path); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"plan":    plan,
+		"written": path,
+		"next":    fmt.Sprintf("Review the plan, then run '%s apply %s' to delete the branches.", AppName, path),
+	}, nil
+}
+
+// selectionFromArguments turns tool arguments into a selection by way of the
+// equivalent selection flags, so both are interpreted the same.
+func selectionFromArguments(args map[string]interface{}) (selection, error) {
+	var flags []string
+	for _, arg := range selectionArguments {
+		flag := "--" + strings.ReplaceAll(arg.name, "_", "-")
+		if set, _ := args[arg.name].(bool); set && arg.boolean {
+			flags = append(flags, flag)
+		}
+		if value, _ := args[arg.name].(string); value != "" && !arg.boolean {
+			flags = append(flags, flag, value)
+		}
+	}
+
+	sel, rest, err := parseSelection(flags)
+	if err != nil {
+		return sel, err
+	}
+	if len(rest) > 0 {
+		return sel, fmt.Errorf("unexpected arguments %v", rest)
+	}
+	sel.pattern, _ = args["pattern"].(string)
+	return sel, nil
+}
+
+func objectSchema(properties map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+// withProperties returns a copy of properties with extra added.
+func withProperties(properties, extra map[string]interface{}) map[string]interface{} {
+	all := make(map[string]interface{}, len(properties)+len(extra))
+	for name, schema := range properties {
+		all[name] = schema
+	}
+	for name, schema := range extra {
+		all[name] = schema
+	}
+	return all
+}
+
+// buildVersion returns the module version the binary was built from.
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"strconv"
+)
+
+// runMergedInto lists the local branches whose tips are reachable from a
+// target branch, tag or commit, numbered by their index in `list`.
+func runMergedInto(args []string) {
+	if len(args) != 1 {
+		usageError("merged-into")
+	}
+	target := args[0]
+	if _, err := strconv.Atoi(target); err == nil {
+		branch, err := resolveBranchArg(target)
+		if err != nil {
+			fatal(err)
+		}
+		target = branch
+	}
+	if gitQuiet("rev-parse", "--verify", "--quiet", target+"^{commit}") != nil {
+		fatalf("Unknown branch, tag or commit %s", target)
+	}
+
+	merged, err := mergedBranches(target)
+	if err != nil {
+		warn("Error listing branches: %s", err)
+		exit(1)
+	}
+	showMergedBranches(target, removeName(merged, target))
+}
+
+// runMerged lists the branches merged into a base branch, by default the
+// primary one, and with --delete deletes them like delete does.
+func runMerged(args []string) {
+	opts, rest := parseDeleteOptions(args, false)
+	del, rest := extractFlag(rest, "--delete")
+	if len(rest) > 1 {
+		usageError("merged")
+	}
+	base := baseBranch()
+	if len(rest) == 1 {
+		branch, err := resolveBranchArg(rest[0])
+		if err != nil {
+			fatal(err)
+		}
+		base = branch
+	}
+
+	merged, err := mergedBranches(base)
+	var current string
+	if err == nil {
+		_, current, err = listBranches()
+	}
+	if err != nil {
+		warn("Error listing branches: %s", err)
+		exit(1)
+	}
+	merged = removeName(merged, base)
+	if !del {
+		showMergedBranches(base, removeName(merged, current))
+		return
+	}
+	// Other base branches are often merged into base too, but are never
+	// what --delete is after.
+	merged = withoutBasesAndCurrent(filterCurrentBranch(merged, current))
+	if len(merged) == 0 {
+		status("No other branches are merged into %s.", base)
+		return
+	}
+	sortBranches(merged)
+	confirmAndDeleteBranches(merged, current, opts)
+}
+
+// showMergedBranches lists the branches merged into target, numbered by
+// their index in `list`.
+func showMergedBranches(target string, merged []string) {
+	all, _, err := listBranches()
+	var infos map[string]branchInfo
+	if err == nil {
+		infos, err = loadBranchInfos(merged)
+	}
+	if err != nil {
+		warn("Error listing branches: %s", err)
+		exit(1)
+	}
+
+	if len(merged) == 0 {
+		status("No other branches are merged into %s.", target)
+		return
+	}
+
+	sortBranches(all)
+	index := make(map[string]int, len(all))
+	for i, branch := range all {
+		index[branch] = i + 1
+	}
+
+	warnIfFetchIsStale()
+	warnIfShallow()
+	sortBranches(merged)
+	title("Branches merged into %s", target)
+	for i, branch := range merged {
+		b := infos[branch]
+		if plainOutput {
+			info("branch %d of %d: %s (index %d), last commit %s", i+1, len(merged), branch, index[branch], formatWhen(b.Date))
+		} else {
+			info("%2d. %s  (%s)", index[branch], branch, formatWhen(b.Date))
+		}
+	}
+}
+
+// removeName returns names without name.
+func removeName(names []string, name string) []string {
+	var rest []string
+	for _, n := range names {
+		if n != name {
+			rest = append(rest, n)
+		}
+	}
+	return rest
+}
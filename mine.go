@@ -0,0 +1,24 @@
+package main
+
+import "strings"
+
+// myIdentities returns the names and emails that identify the current user:
+// the configured git user plus any identities listed in the config.
+func myIdentities() []string {
+	identities := append([]string(nil), config().Identities...)
+	for _, key := range []string{"user.email", "user.name"} {
+		if lines, err := gitLines("config", "--get", key); err == nil && len(lines) > 0 {
+			identities = append(identities, lines[0])
+		}
+	}
+	return identities
+}
+
+func isMine(b branchInfo, identities []string) bool {
+	for _, identity := range identities {
+		if strings.EqualFold(identity, b.Email) || strings.EqualFold(identity, b.Author) {
+			return true
+		}
+	}
+	return false
+}
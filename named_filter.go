@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// namedFilter is a saved selection from the config's filters section, keyed
+// by selection flag name without dashes, plus "pattern". It may be written as
+// a mapping or as a single line such as "pattern=tmp/* older-than=7d merged".
+type namedFilter map[string]string
+
+func (f *namedFilter) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*f = make(namedFilter)
+		for _, field := range strings.Fields(node.Value) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				value = "true"
+			}
+			(*f)[key] = value
+		}
+		return nil
+	}
+
+	var m map[string]string
+	if err := node.Decode(&m); err != nil {
+		return err
+	}
+	*f = m
+	return nil
+}
+
+// args converts the filter into the equivalent command-line arguments.
+func (f namedFilter) args() []string {
+	var keys []string
+	for key := range f {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var args []string
+	for _, key := range keys {
+		value := f[key]
+		switch {
+		case key == "pattern":
+			args = append(args, value)
+		case value == "true":
+			args = append(args, "--"+key)
+		case value == "false":
+		default:
+			args = append(args, "--"+key, value)
+		}
+	}
+	return args
+}
+
+// expandNamedFilters replaces each @name argument with the arguments of the
+// saved filter called name.
+func expandNamedFilters(args []string) ([]string, error) {
+	var expanded []string
+	for _, arg := range args {
+		name, ok := strings.CutPrefix(arg, "@")
+		if !ok || name == "" {
+			expanded = append(expanded, arg)
+			continue
+		}
+		filter, ok := config().Filters[name]
+		if !ok {
+			return nil, fmt.Errorf("no saved filter named %q", name)
+		}
+		expanded = append(expanded, filter.args()...)
+	}
+	return expanded, nil
+}
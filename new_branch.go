@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NewBranchConfig controls branches created with `new`.
+type NewBranchConfig struct {
+	// Upstream is the tracking policy: none, from (track the base branch),
+	// inherit (copy the base branch's upstream) or push (publish to the
+	// remote and track the pushed branch).
+	Upstream string `yaml:"upstream"`
+}
+
+func runNew(args []string) {
+	from, _, args := extractOption(args, "--from")
+	upstream, ok, args := extractOption(args, "--upstream")
+	if !ok {
+		upstream = config().NewBranch.Upstream
+	}
+	switchTo, args := extractFlag(args, "--switch")
+	if len(args) != 1 {
+		usageError("new")
+	}
+	name := args[0]
+
+	base, err := resolveStartPoint(from)
+	if err != nil {
+		warn(err.Error())
+		exit(1)
+	}
+
+	branchArgs := []string{"branch"}
+	switch upstream {
+	case "", "none", "push":
+		branchArgs = append(branchArgs, "--no-track")
+	case "from":
+		branchArgs = append(branchArgs, "--track")
+	case "inherit":
+		branchArgs = append(branchArgs, "--track=inherit")
+	default:
+		fatalf("Unknown upstream policy %q. Use none, from, inherit or push.", upstream)
+	}
+
+	if err := gitRun(append(branchArgs, name, base)...); err != nil {
+		exit(1)
+	}
+	info("Created branch %s from %s", name, base)
+
+	if upstream == "push" {
+		if err := gitRun("push", "-u", remoteName, name); err != nil {
+			warn("Branch created, but pushing it to %s failed.", remoteName)
+			exit(1)
+		}
+	}
+
+	if switchTo {
+		if err := gitRun("switch", name); err != nil {
+			exit(1)
+		}
+	}
+}
+
+// resolveStartPoint turns a --from value into a revision: a local branch
+// index, a remote branch index (rN), or any branch name or commit.
+func resolveStartPoint(from string) (string, error) {
+	if from == "" {
+		return "HEAD", nil
+	}
+	if _, err := strconv.Atoi(from); err == nil {
+		return resolveBranchArg(from)
+	}
+	if strings.HasPrefix(from, "r") {
+		if _, err := strconv.Atoi(from[1:]); err == nil {
+			return resolveRemoteIndex(from)
+		}
+	}
+	if _, err := gitLines("rev-parse", "--verify", "--quiet", from+"^{commit}"); err != nil {
+		return "", fmt.Errorf("%s is not a branch, index or commit", from)
+	}
+	return from, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// NotifyConfig lists where a summary is sent after a cleanup run. Any
+// combination may be set, in the global config or git config but not in a
+// repository's config file.
+type NotifyConfig struct {
+	// Slack is a Slack incoming webhook URL.
+	Slack string `yaml:"slack"`
+	// Webhook is a URL that receives the summary as JSON.
+	Webhook string `yaml:"webhook"`
+	// Command is run by the shell with the summary as JSON on stdin.
+	Command string `yaml:"command"`
+}
+
+func (c NotifyConfig) enabled() bool {
+	return c.Slack != "" || c.Webhook != "" || c.Command != ""
+}
+
+// cleanupSummary describes the outcome of a cleanup run.
+type cleanupSummary struct {
+	Repository    string              `json:"repository"`
+	Time          time.Time           `json:"time"`
+	Deleted       []string            `json:"deleted"`
+	Failed        []string            `json:"failed"`
+	ObjectsBefore int                 `json:"objects_before"`
+	ObjectsAfter  int                 `json:"objects_after"`
+	SizeBeforeKiB int                 `json:"size_before_kib"`
+	SizeAfterKiB  int                 `json:"size_after_kib"`
+	StaleByAuthor map[string][]string `json:"stale_by_author"`
+}
+
+// text renders the summary as a short message for chat.
+func (s cleanupSummary) text() string {
+	var b strings.Builder
+	branchStr := "branches"
+	if len(s.Deleted) == 1 {
+		branchStr = "branch"
+	}
+	fmt.Fprintf(&b, "%s cleanup in %s: %d %s deleted", AppName, s.Repository, len(s.Deleted), branchStr)
+	if len(s.Deleted) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(s.Deleted, ", "))
+	}
+	if len(s.Failed) > 0 {
+		fmt.Fprintf(&b, ", %d could not be deleted (%s)", len(s.Failed), strings.Join(s.Failed, ", "))
+	}
+	fmt.Fprintf(&b, ". Repository size %d KiB, was %d KiB.", s.SizeAfterKiB, s.SizeBeforeKiB)
+
+	var authors []string
+	for author := range s.StaleByAuthor {
+		authors = append(authors, author)
+	}
+	sort.Strings(authors)
+	if len(authors) > 0 {
+		b.WriteString("\nStale branches:")
+		for _, author := range authors {
+			fmt.Fprintf(&b, "\n  %s: %s", author, strings.Join(s.StaleByAuthor[author], ", "))
+		}
+	}
+	return b.String()
+}
+
+// notifyCleanup sends a summary of a run that tried to delete attempted to
+// the configured notifiers. before is the object count from before the run.
+func notifyCleanup(attempted []string, before objectStats) {
+	cfg := config().Notify
+	if !cfg.enabled() {
+		return
+	}
+
+	summary, err := summarizeCleanup(attempted, before)
+	if err != nil {
+		warn("Error preparing notification: %s", err)
+		return
+	}
+
+	if cfg.Slack != "" {
+		if err := postJSON(cfg.Slack, map[string]string{"text": summary.text()}); err != nil {
+			warn("Error notifying Slack: %s", err)
+		}
+	}
+	if cfg.Webhook != "" {
+		if err := postJSON(cfg.Webhook, summary); err != nil {
+			warn("Error notifying webhook: %s", err)
+		}
+	}
+	if cfg.Command != "" {
+		if err := runNotifyCommand(cfg.Command, summary); err != nil {
+			warn("Error running notify command: %s", err)
+		}
+	}
+}
+
+func summarizeCleanup(attempted []string, before objectStats) (cleanupSummary, error) {
+	summary := cleanupSummary{
+		Time:          time.Now(),
+		Deleted:       []string{},
+		Failed:        []string{},
+		ObjectsBefore: before.count,
+		SizeBeforeKiB: before.sizeKiB,
+		StaleByAuthor: map[string][]string{},
+	}
+	if lines, err := gitLines("rev-parse", "--show-toplevel"); err == nil && len(lines) > 0 {
+		summary.Repository = filepath.Base(lines[0])
+	}
+
+	after, err := countObjects()
+	if err != nil {
+		return summary, err
+	}
+	summary.ObjectsAfter, summary.SizeAfterKiB = after.count, after.sizeKiB
+
+	refs, err := readBranchRefs()
+	if err != nil {
+		return summary, err
+	}
+	remaining := make(map[string]bool, len(refs))
+	for _, b := range refs {
+		remaining[b.Name] = true
+		if time.Since(b.Date) >= staleAfter {
+			summary.StaleByAuthor[b.Author] = append(summary.StaleByAuthor[b.Author], b.Name)
+		}
+	}
+	for _, branch := range attempted {
+		if remaining[branch] {
+			summary.Failed = append(summary.Failed, branch)
+		} else {
+			summary.Deleted = append(summary.Deleted, branch)
+		}
+	}
+	return summary, nil
+}
+
+func postJSON(url string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	client := newHTTPClient(15 * time.Second)
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// runNotifyCommand runs command with the summary as JSON on stdin and as
+// text in the <APP>_SUMMARY environment variable.
+func runNotifyCommand(command string, summary cleanupSummary) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout, cmd.Stderr = color.Output, os.Stderr
+	cmd.Env = append(os.Environ(), strings.ToUpper(AppName)+"_SUMMARY="+summary.text())
+	return cmd.Run()
+}
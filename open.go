@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+func runOpen(args []string) {
+	target := config().Hosting.Open
+	for _, mode := range []string{"branch", "pr", "compare"} {
+		var set bool
+		if set, args = extractFlag(args, "--"+mode); set {
+			target = mode
+		}
+	}
+	printOnly, args := extractFlag(args, "--print")
+	if len(args) != 1 {
+		usageError("open")
+	}
+
+	branch, err := resolveBranchArg(args[0])
+	if err != nil {
+		warn(err.Error())
+		exit(1)
+	}
+
+	link, err := hostingURL(branch, target)
+	if err != nil {
+		warn("Cannot build a link for %s: %s", branch, err)
+		exit(1)
+	}
+
+	if printOnly {
+		fmt.Println(link)
+		return
+	}
+	info("Opening %s", link)
+	if err := openBrowser(link); err != nil {
+		warn("Cannot open a browser: %s", err)
+		exit(1)
+	}
+}
+
+// resolveBranchArg accepts a branch name, its 1-based index in the sorted
+// output of `list`, or an hN index from `recent`.
+func resolveBranchArg(arg string) (string, error) {
+	if recent, ok := strings.CutPrefix(arg, "h"); ok && !branchExists(arg) {
+		if _, err := strconv.Atoi(recent); err == nil {
+			return resolveRecentIndex(arg)
+		}
+	}
+
+	index, err := strconv.Atoi(arg)
+	if err != nil {
+		if !branchExists(arg) {
+			return "", fmt.Errorf("no such branch %s", arg)
+		}
+		return arg, nil
+	}
+
+	branches, _, err := listBranches()
+	if err != nil {
+		return "", err
+	}
+	sortBranches(branches)
+	if index < 1 || index > len(branches) {
+		return "", fmt.Errorf("index %d is out of range; run '%s list' to see branch indexes", index, AppName)
+	}
+	return branches[index-1], nil
+}
+
+// hostingURL returns the web page for branch on the remote's hosting service:
+// the branch itself, its pull request, or a comparison against the base.
+func hostingURL(branch, target string) (string, error) {
+	loc, err := remoteLocation(remoteName)
+	if err != nil {
+		return "", err
+	}
+	repoURL := "https://" + loc.host + "/" + loc.owner + "/" + loc.name
+	base := strings.TrimPrefix(baseBranch(), remoteName+"/")
+	escaped := escapeBranch(branch)
+
+	provider := config().Hosting.Provider
+	switch {
+	case provider == "gitlab" || strings.Contains(loc.host, "gitlab"):
+		switch target {
+		case "pr":
+			return repoURL + "/-/merge_requests?scope=all&state=opened&source_branch=" + url.QueryEscape(branch), nil
+		case "compare":
+			return repoURL + "/-/compare/" + escapeBranch(base) + "..." + escaped, nil
+		}
+		return repoURL + "/-/tree/" + escaped, nil
+	case provider == "bitbucket" || strings.Contains(loc.host, "bitbucket"):
+		switch target {
+		case "pr", "compare":
+			return repoURL + "/branches/compare/" + escaped + "%0D" + escapeBranch(base), nil
+		}
+		return repoURL + "/src/" + escaped, nil
+	default:
+		switch target {
+		case "pr":
+			if number, ok := openPullRequestsOrWarn()[branch]; ok {
+				return fmt.Sprintf("%s/pull/%d", repoURL, number), nil
+			}
+			return repoURL + "/compare/" + escapeBranch(base) + "..." + escaped + "?expand=1", nil
+		case "compare":
+			return repoURL + "/compare/" + escapeBranch(base) + "..." + escaped, nil
+		}
+		return repoURL + "/tree/" + escaped, nil
+	}
+}
+
+// escapeBranch escapes each path segment of a branch name for use in a URL.
+func escapeBranch(branch string) string {
+	segments := strings.Split(branch, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// openBrowser opens link in $BROWSER or the platform's default browser.
+func openBrowser(link string) error {
+	var cmd *exec.Cmd
+	switch browser := os.Getenv("BROWSER"); {
+	case browser != "":
+		cmd = exec.Command(browser, link)
+	case runtime.GOOS == "darwin":
+		cmd = exec.Command("open", link)
+	case runtime.GOOS == "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", link)
+	default:
+		cmd = exec.Command("xdg-open", link)
+	}
+	return cmd.Start()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// orgConfigMaxAge is how long a fetched organization config is used before
+// it is fetched again.
+const orgConfigMaxAge = 24 * time.Hour
+
+// maxOrgConfigSize bounds the organization config download.
+const maxOrgConfigSize = 1 << 20
+
+// configURL returns the config_url set in the global config or git config,
+// which is needed before the rest of the configuration can be layered over
+// the organization's. A repository's config file cannot set it, or a cloned
+// repository could choose the config, notify commands included.
+func configURL() string {
+	var url string
+	if data, err := os.ReadFile(globalConfigPath()); err == nil {
+		var cfg struct {
+			ConfigURL string `yaml:"config_url"`
+		}
+		// Invalid files are reported when they are read in full.
+		if yaml.Unmarshal(data, &cfg) == nil {
+			url = cfg.ConfigURL
+		}
+	}
+	if lines, err := gitLines("config", "--get", AppName+".config-url"); err == nil && len(lines) > 0 {
+		url = lines[0]
+	}
+	return url
+}
+
+// orgConfig returns the organization config at rawURL, from the cache while
+// it is fresh. When it cannot be fetched, a stale cached copy is used.
+func orgConfig(rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("config_url must be an https URL")
+	}
+
+	cache := orgConfigCachePath(rawURL)
+	cached, cacheErr := os.ReadFile(cache)
+	if cacheErr == nil {
+		if stat, err := os.Stat(cache); err == nil && time.Since(stat.ModTime()) < orgConfigMaxAge {
+			return cached, nil
+		}
+	}
+
+	data, err := fetchOrgConfig(rawURL)
+	if err != nil {
+		if cacheErr == nil {
+			warn("Using the cached organization config; fetching %s failed: %s", describeConfigURL(rawURL), err)
+			return cached, nil
+		}
+		return nil, err
+	}
+	if cache != "" {
+		if err := os.MkdirAll(filepath.Dir(cache), 0o755); err == nil {
+			_ = os.WriteFile(cache, data, 0o644)
+		}
+	}
+	return data, nil
+}
+
+func fetchOrgConfig(rawURL string) ([]byte, error) {
+	client := newHTTPClient(10 * time.Second)
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			// The URL is reported separately, without credentials.
+			return nil, urlErr.Err
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxOrgConfigSize))
+	if err != nil {
+		return nil, err
+	}
+	// Check it parses before caching it.
+	var check Config
+	if err := yaml.Unmarshal(data, &check); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return data, nil
+}
+
+// orgConfigCachePath returns where the config fetched from rawURL is cached,
+// or "" if there is no cache directory.
+func orgConfigCachePath(rawURL string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(dir, AppName, "org-config", hex.EncodeToString(sum[:8])+".yaml")
+}
+
+// describeConfigURL names the source of the organization config for
+// messages, leaving out credentials the URL may carry.
+func describeConfigURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "config_url"
+	}
+	u.User, u.RawQuery = nil, ""
+	return u.String()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strconv"
+)
+
+// defaultPerPage is the page size used when --page is given without
+// --per-page.
+const defaultPerPage = 50
+
+// parsePageOptions extracts --page and --per-page. perPage is 0 when neither
+// is given, meaning no pagination.
+func parsePageOptions(args []string) (page, perPage int, rest []string) {
+	pageArg, hasPage, rest := extractOption(args, "--page")
+	perPageArg, hasPerPage, rest := extractOption(rest, "--per-page")
+	if !hasPage && !hasPerPage {
+		return 0, 0, rest
+	}
+
+	page, perPage = 1, defaultPerPage
+	var err error
+	if hasPage {
+		if page, err = strconv.Atoi(pageArg); err != nil || page < 1 {
+			fatalf("Invalid --page %q: must be a positive number", pageArg)
+		}
+	}
+	if hasPerPage {
+		if perPage, err = strconv.Atoi(perPageArg); err != nil || perPage < 1 {
+			fatalf("Invalid --per-page %q: must be a positive number", perPageArg)
+		}
+	}
+	return page, perPage, rest
+}
+
+// pageOf returns the branches on the given page. Pages past the end are
+// empty; the checks keep a huge --page or --per-page from overflowing.
+func pageOf(branches []string, page, perPage int) []string {
+	start := len(branches)
+	if page-1 <= len(branches)/perPage {
+		start = min((page-1)*perPage, len(branches))
+	}
+	end := start + min(perPage, len(branches)-start)
+	return branches[start:end]
+}
+
+func showPageFooter(page, perPage, total int) {
+	pages := 1
+	if total > 0 {
+		pages = (total-1)/perPage + 1
+	}
+	if page < pages {
+		status("\nPage %d of %d. Use --page %d for more.", page, pages, page+1)
+	} else {
+		status("\nPage %d of %d.", page, pages)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestPageOf(t *testing.T) {
+	branches := []string{"a", "b", "c", "d", "e"}
+	tests := []struct {
+		page, perPage int
+		want          []string
+	}{
+		{1, 2, []string{"a", "b"}},
+		{3, 2, []string{"e"}},
+		{4, 2, []string{}},
+		{1, math.MaxInt, branches},
+		{2, math.MaxInt, []string{}},
+		{math.MaxInt, 2, []string{}},
+		{math.MaxInt/2 + 2, 2, []string{}},
+	}
+	for _, tt := range tests {
+		if got := pageOf(branches, tt.page, tt.perPage); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("pageOf(page %d, per page %d) = %q, want %q", tt.page, tt.perPage, got, tt.want)
+		}
+	}
+}
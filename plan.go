@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// deletionPlan is a reviewable list of intended deletions written by `plan`
+// and carried out by `apply`.
+type deletionPlan struct {
+	Created  time.Time    `json:"created"`
+	Force    bool         `json:"force"`
+	Branches []plannedRef `json:"branches"`
+}
+
+type plannedRef struct {
+	Name    string   `json:"name"`
+	SHA     string   `json:"sha"`
+	Reasons []string `json:"reasons"`
+}
+
+func runPlan(args []string) {
+	force, args := extractFlag(args, "--force")
+	out, _, args := extractOption(args, "--out")
+	sel, rest, err := parseSelection(args)
+	if err != nil {
+		fatal(err)
+	}
+	if len(rest) == 1 {
+		sel.pattern = rest[0]
+	}
+	if len(rest) > 1 || out == "" || (sel.pattern == "" && !sel.hasFilters()) {
+		usageError("plan")
+	}
+
+	plan, err := newDeletionPlan(sel, force)
+	if err != nil {
+		warn("Error making plan: %s", err)
+		exit(1)
+	}
+	if err := writePlan(plan, out); err != nil {
+		warn("Error writing plan: %s", err)
+		exit(1)
+	}
+
+	status("Plan to delete %s written to %s. Review it, then run '%s apply %s'.", branchCount(len(plan.Branches)), out, AppName, out)
+}
+
+// newDeletionPlan plans the deletion of the branches selected by sel, leaving
+// out the current and protected branches.
+func newDeletionPlan(sel selection, force bool) (deletionPlan, error) {
+	branches, currentBranch, err := listBranches()
+	if err == nil {
+		branches, err = sel.filter(branches)
+	}
+	if err != nil {
+		return deletionPlan{}, err
+	}
+	branches = filterCurrentBranch(branches, currentBranch)
+	branches = filterProtectedBranches(branches)
+
+	infos, err := loadBranchInfos(branches)
+	if err != nil {
+		return deletionPlan{}, err
+	}
+
+	sortBranches(branches)
+	plan := deletionPlan{Created: time.Now(), Force: force, Branches: []plannedRef{}}
+	for _, branch := range branches {
+		b := infos[branch]
+		reasons := append(sel.reasons(), fmt.Sprintf("last commit %s by %s, %s", formatWhen(b.Date), b.Author, b.mergedLabel()))
+		plan.Branches = append(plan.Branches, plannedRef{Name: branch, SHA: b.SHA, Reasons: reasons})
+	}
+	return plan, nil
+}
+
+// writePlan saves plan as JSON to path.
+func writePlan(plan deletionPlan, path string) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// writeNewPlan is writePlan for a file that must not exist yet.
+func writeNewPlan(plan deletionPlan, path string) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// plansDir is where plans made through mcp are written, so that a client
+// can only add files there rather than overwrite any file the user can.
+func plansDir() (string, error) {
+	common, err := gitCommonDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(common, AppName+"-plans"), nil
+}
+
+func runApply(args []string) {
+	if len(args) != 1 {
+		usageError("apply")
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		warn("Error reading plan: %s", err)
+		exit(1)
+	}
+	var plan deletionPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		warn("Error parsing plan %s: %s", args[0], err)
+		exit(1)
+	}
+	if len(plan.Branches) == 0 {
+		status("The plan has no branches to delete.")
+		return
+	}
+
+	if drift := planDrift(plan); len(drift) > 0 {
+		warn("The repository has changed since the plan was made on %s:", plan.Created.Format("2006-01-02 15:04"))
+		for _, problem := range drift {
+			warn("    %s", problem)
+		}
+		warn("Nothing was deleted. Make a new plan.")
+		exit(1)
+	}
+
+	var branches []string
+	for _, b := range plan.Branches {
+		branches = append(branches, b.Name)
+	}
+	branches, ok := confirmBranchesToDelete(branches, nil)
+	if !ok {
+		return
+	}
+	before, _ := countObjects()
+	deleteBranches(branches, plan.Force)
+	notifyCleanup(branches, before)
+}
+
+// planDrift returns a description of each way the repository no longer
+// matches plan, such as a branch that moved or became protected.
+func planDrift(plan deletionPlan) []string {
+	refs, err := readBranchRefs()
+	if err != nil {
+		warn("Error listing branches: %s", err)
+		exit(1)
+	}
+	current := make(map[string]branchInfo, len(refs))
+	for _, b := range refs {
+		current[b.Name] = b
+	}
+	patterns := protectedPatterns()
+	busy := branchesInProgress()
+
+	var drift []string
+	for _, planned := range plan.Branches {
+		b, ok := current[planned.Name]
+		switch {
+		case !ok:
+			drift = append(drift, planned.Name+" no longer exists")
+		case b.SHA != planned.SHA:
+			drift = append(drift, fmt.Sprintf("%s moved from %s to %s", planned.Name, shortSHA(planned.SHA), shortSHA(b.SHA)))
+		case b.Current:
+			drift = append(drift, planned.Name+" is now checked out")
+		case isProtected(planned.Name, patterns):
+			drift = append(drift, planned.Name+" is now protected")
+		case busy[planned.Name] != "":
+			drift = append(drift, fmt.Sprintf("%s has a %s", planned.Name, busy[planned.Name]))
+		}
+	}
+	return drift
+}
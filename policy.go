@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PolicyConfig describes branch hygiene rules that list and check report on.
+type PolicyConfig struct {
+	// MaxBranches is the most local branches the repository may have; 0
+	// means no limit.
+	MaxBranches int `yaml:"max_branches"`
+	// MaxAge maps branch patterns to the longest a matching branch may go
+	// without new commits, e.g. "feature/*": 14d. When several patterns
+	// match a branch, the most specific one applies.
+	MaxAge map[string]string `yaml:"max_age"`
+	// RequiredPrefixes, if set, are the prefixes branch names must start
+	// with, e.g. feature/ and fix/. Protected and base branches are exempt.
+	RequiredPrefixes []string `yaml:"required_prefixes"`
+	// RequireUpstream lists patterns of branches that must track a branch
+	// that still exists on the remote.
+	RequireUpstream []string `yaml:"require_upstream"`
+	// Severity overrides the severity, error or warning, of each rule.
+	Severity map[string]string `yaml:"severity"`
+	CI       CIThresholds      `yaml:"ci"`
+}
+
+// CIThresholds are how many violations check --ci tolerates before failing.
+type CIThresholds struct {
+	MaxErrors int `yaml:"max_errors" json:"max_errors"`
+	// MaxWarnings is -1, the default, for no limit.
+	MaxWarnings int `yaml:"max_warnings" json:"max_warnings"`
+}
+
+// defaultSeverities are the severities of the policy rules unless the
+// configuration overrides them.
+var defaultSeverities = map[string]string{
+	"max_branches":      "error",
+	"max_age":           "warning",
+	"required_prefixes": "error",
+	"require_upstream":  "warning",
+}
+
+// policyViolation is one way the repository breaks a policy.
+type policyViolation struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	// Branch is empty for rules about the repository as a whole.
+	Branch  string `json:"branch,omitempty"`
+	Message string `json:"message"`
+}
+
+// checkReport is the result of check --ci.
+type checkReport struct {
+	Passed     bool              `json:"passed"`
+	Errors     int               `json:"errors"`
+	Warnings   int               `json:"warnings"`
+	Thresholds CIThresholds      `json:"thresholds"`
+	Violations []policyViolation `json:"violations"`
+}
+
+// ageViolation describes a branch that is older than its policy allows.
+type ageViolation struct {
+	branch  string
+	pattern string
+	age     time.Duration
+	limit   time.Duration
+}
+
+// parseAge parses durations such as "90d", "2w" or "36h".
+func parseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// maxAgeFor returns the max-age policy matching branch. The most specific
+// pattern, the one with the most characters other than wildcards, wins so
+// that a narrower pattern can loosen or tighten a broader one; among equally
+// specific ones the strictest wins.
+func maxAgeFor(branch string) (time.Duration, string, bool) {
+	policies := config().Policies.MaxAge
+	patterns := make([]string, 0, len(policies))
+	for pattern := range policies {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	var limit time.Duration
+	var matched string
+	for _, pattern := range patterns {
+		if !matchesPattern(branch, pattern) {
+			continue
+		}
+		d, err := parseAge(policies[pattern])
+		if err != nil {
+			warn("Ignoring max_age policy for %s: %s", pattern, err)
+			continue
+		}
+		switch {
+		case matched == "",
+			patternSpecificity(pattern) > patternSpecificity(matched),
+			patternSpecificity(pattern) == patternSpecificity(matched) && d < limit:
+			limit, matched = d, pattern
+		}
+	}
+	return limit, matched, matched != ""
+}
+
+// patternSpecificity counts the characters of pattern other than wildcards.
+func patternSpecificity(pattern string) int {
+	return len(pattern) - strings.Count(pattern, "*")
+}
+
+// ageViolations returns the branches whose last commit is older than their
+// max-age policy allows, keyed by branch.
+func ageViolations(dates map[string]time.Time) map[string]ageViolation {
+	violations := make(map[string]ageViolation)
+	if len(config().Policies.MaxAge) == 0 {
+		return violations
+	}
+
+	for branch, date := range dates {
+		limit, pattern, ok := maxAgeFor(branch)
+		if !ok {
+			continue
+		}
+		if age := time.Since(date); age > limit {
+			violations[branch] = ageViolation{branch: branch, pattern: pattern, age: age, limit: limit}
+		}
+	}
+	return violations
+}
+
+func (v ageViolation) String() string {
+	return fmt.Sprintf("%s is %s old, over the %s limit for %s", v.branch, humanizeDuration(v.age), formatDuration(v.limit), v.pattern)
+}
+
+func runCheck(args []string) {
+	ci, args := extractFlag(args, "--ci")
+	if len(args) != 0 {
+		usageError("check")
+	}
+
+	violations, err := evaluatePolicies()
+	if err != nil {
+		warn("Error listing branches: %s", err)
+		exit(1)
+	}
+	errors := 0
+	for _, v := range violations {
+		if v.Severity == "error" {
+			errors++
+		}
+	}
+
+	if ci {
+		reportCheck(violations, errors)
+		return
+	}
+
+	if len(violations) == 0 {
+		status("No policy violations.")
+		return
+	}
+	title("Policy violations:")
+	for _, v := range violations {
+		if v.Severity == "error" {
+			warn("error: %s (%s)", v.Message, v.Rule)
+		} else {
+			info("%s: %s (%s)", v.Severity, v.Message, v.Rule)
+		}
+	}
+	status("%s: %s, %s.", plural(len(violations), "policy violation"), plural(errors, "error"), plural(len(violations)-errors, "warning"))
+	if errors > 0 {
+		exit(1)
+	}
+}
+
+// reportCheck prints the violations as JSON for CI pipelines and exits with
+// status 1 if there are more than the configured thresholds allow.
+func reportCheck(violations []policyViolation, errors int) {
+	thresholds := config().Policies.CI
+	report := checkReport{
+		Errors:     errors,
+		Warnings:   len(violations) - errors,
+		Thresholds: thresholds,
+		Violations: violations,
+	}
+	if report.Violations == nil {
+		report.Violations = []policyViolation{}
+	}
+	report.Passed = report.Errors <= thresholds.MaxErrors &&
+		(thresholds.MaxWarnings < 0 || report.Warnings <= thresholds.MaxWarnings)
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fatal("Error encoding report:", err)
+	}
+	fmt.Println(string(data))
+	if !report.Passed {
+		exit(1)
+	}
+}
+
+// evaluatePolicies checks every local branch against the configured
+// policies and returns the violations, errors first.
+func evaluatePolicies() ([]policyViolation, error) {
+	refs, err := readBranchRefs()
+	if err != nil {
+		return nil, err
+	}
+	policies := config().Policies
+	var violations []policyViolation
+	add := func(rule, branch, format string, a ...interface{}) {
+		violations = append(violations, policyViolation{
+			Rule:     rule,
+			Severity: policySeverity(rule),
+			Branch:   branch,
+			Message:  fmt.Sprintf(format, a...),
+		})
+	}
+
+	if policies.MaxBranches > 0 && len(refs) > policies.MaxBranches {
+		add("max_branches", "", "%d local branches, over the limit of %d", len(refs), policies.MaxBranches)
+	}
+
+	dates := make(map[string]time.Time, len(refs))
+	for _, b := range refs {
+		dates[b.Name] = b.Date
+	}
+	for branch, v := range ageViolations(dates) {
+		add("max_age", branch, "%s", v)
+	}
+
+	protected := protectedPatterns()
+	bases := baseBranches()
+	for _, b := range refs {
+		exempt := isProtected(b.Name, protected) || contains(bases, b.Name)
+		if len(policies.RequiredPrefixes) > 0 && !exempt && !hasAnyPrefix(b.Name, policies.RequiredPrefixes) {
+			add("required_prefixes", b.Name, "%s does not start with %s", b.Name, strings.Join(policies.RequiredPrefixes, " or "))
+		}
+		// isProtected is used here only to match the branch against
+		// the patterns.
+		if !exempt && isProtected(b.Name, policies.RequireUpstream) {
+			switch {
+			case b.Upstream == "":
+				add("require_upstream", b.Name, "%s has no upstream", b.Name)
+			case b.Track == "gone":
+				add("require_upstream", b.Name, "%s tracks %s, which is gone", b.Name, b.Upstream)
+			}
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		if a.Severity != b.Severity {
+			return a.Severity == "error"
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.Branch < b.Branch
+	})
+	return violations, nil
+}
+
+// policySeverity returns the configured severity of rule.
+func policySeverity(rule string) string {
+	switch severity := config().Policies.Severity[rule]; severity {
+	case "":
+	case "error", "warning":
+		return severity
+	default:
+		warn("Ignoring severity %q for %s; use error or warning.", severity, rule)
+	}
+	return defaultSeverities[rule]
+}
+
+func hasAnyPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
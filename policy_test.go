@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	const day = 24 * time.Hour
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"90d", 90 * day, true},
+		{"0d", 0, true},
+		{"2w", 14 * day, true},
+		{"36h", 36 * time.Hour, true},
+		{"1h30m", 90 * time.Minute, true},
+		{"-1d", 0, false},
+		{"1.5d", 0, false},
+		{"d", 0, false},
+		{"90", 0, false},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseAge(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseAge(%q) = %s, %v; want %s, ok %v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMaxAgeFor(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Policies.MaxAge = map[string]string{
+		"*":              "90d",
+		"feature/*":      "14d",
+		"feature/long/*": "60d",
+		"fix/*":          "7d",
+		"*fix/*":         "3d",
+	}
+	loadedConfig = &cfg
+	defer func() { loadedConfig = nil }()
+
+	tests := []struct {
+		branch  string
+		pattern string
+		limit   time.Duration
+	}{
+		{"feature/a", "feature/*", 14 * 24 * time.Hour},
+		{"feature/long/a", "feature/long/*", 60 * 24 * time.Hour},
+		{"wip", "*", 90 * 24 * time.Hour},
+		// fix/* and *fix/* are equally specific, so the stricter wins.
+		{"fix/a", "*fix/*", 3 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		// Repeat to catch a winner that depends on map order.
+		for i := 0; i < 20; i++ {
+			limit, pattern, ok := maxAgeFor(tt.branch)
+			if !ok || pattern != tt.pattern || limit != tt.limit {
+				t.Fatalf("maxAgeFor(%q) = %s, %q, %v; want %s, %q", tt.branch, limit, pattern, ok, tt.limit, tt.pattern)
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// skippedBranch is a selected branch that will not be deleted, and why.
+type skippedBranch struct {
+	name   string
+	reason string
+}
+
+// partitionDeletable splits branches into those that may be deleted and
+// those skipped because they are checked out, protected, part of an
+// unfinished operation or, with safe, have commits missing from every base.
+func partitionDeletable(branches []string, currentBranch string, safe bool) ([]string, []skippedBranch) {
+	patterns := protectedPatterns()
+	busy := branchesInProgress()
+	bases := baseBranches()
+	if safe {
+		if remote := partialCloneRemote(); remote != "" {
+			info("This is a partial clone, so checking for cherry-picked commits may download missing objects from %s.", remote)
+		}
+	}
+
+	var deletable []string
+	var skipped []skippedBranch
+	for _, branch := range branches {
+		reason := ""
+		switch {
+		case branch == currentBranch:
+			reason = "current branch"
+		case isProtected(branch, patterns):
+			reason = "protected"
+		case busy[branch] != "":
+			reason = busy[branch]
+		case safe && !isSafeInAnyBase(branch, bases):
+			reason = "commits not in " + strings.Join(bases, " or ") + " (--safe)"
+		}
+		if reason == "" {
+			deletable = append(deletable, branch)
+		} else {
+			skipped = append(skipped, skippedBranch{branch, reason})
+		}
+	}
+	return deletable, skipped
+}
+
+// showBranchGroup lists one section of the deletion preview, numbering its
+// branches from first out of total, and returns the branches in it that
+// have open pull requests.
+func showBranchGroup(heading string, branches []string, first, total int, infos map[string]branchInfo, prs map[string]int) []string {
+	if len(branches) == 0 {
+		return nil
+	}
+	title("%s: %s", heading, branchCount(len(branches)))
+
+	t := table{alignRight: map[int]bool{0: true}}
+	var withPRs []string
+	for i, branch := range branches {
+		b := infos[branch]
+		date := formatWhen(b.Date)
+		pr := ""
+		if number, ok := prs[branch]; ok {
+			pr = fmt.Sprintf("  [PR #%d]", number)
+			withPRs = append(withPRs, branch)
+		}
+		if plainOutput {
+			info("branch %d of %d: %s, last commit %s by %s, %s%s", first+i, total, branch, date, b.Author, b.mergedLabel(), pr)
+		} else {
+			t.addRow(strconv.Itoa(first+i), branch, date, b.mergedLabel(), b.Author, strings.TrimSpace(pr))
+		}
+	}
+	for _, line := range t.lines() {
+		info("%s", line)
+	}
+	return withPRs
+}
+
+// showSkippedBranches lists the selected branches that will be left alone.
+func showSkippedBranches(skipped []skippedBranch) {
+	if len(skipped) == 0 {
+		return
+	}
+	title("Protected/skipped: %s", branchCount(len(skipped)))
+
+	t := table{}
+	for i, s := range skipped {
+		if plainOutput {
+			info("branch %d of %d: %s, skipped: %s", i+1, len(skipped), s.name, s.reason)
+		} else {
+			t.addRow(s.name, s.reason)
+		}
+	}
+	for _, line := range t.lines() {
+		info("%s", line)
+	}
+}
+
+// branchCount renders n as "1 branch" or "n branches".
+func branchCount(n int) string {
+	if n == 1 {
+		return "1 branch"
+	}
+	return fmt.Sprintf("%d branches", n)
+}
+
+// excludeBranches asks for the numbers, as shown in the preview, of the
+// branches to leave out of a deletion and returns the others.
+func excludeBranches(shown []string) []string {
+	for {
+		answer, err := ask("Enter the numbers to remove from this deletion, e.g. 2,5 or 3-6:")
+		if err != nil {
+			return shown
+		}
+		exclude, err := parseNumberList(answer, len(shown))
+		if err != nil {
+			warn(err.Error())
+			continue
+		}
+
+		var rest []string
+		for i, branch := range shown {
+			if !exclude[i+1] {
+				rest = append(rest, branch)
+			}
+		}
+		return rest
+	}
+}
+
+// parseNumberList parses numbers and ranges between 1 and max separated by
+// commas or spaces, such as "2, 5-7".
+func parseNumberList(list string, max int) (map[int]bool, error) {
+	numbers := make(map[int]bool)
+	fields := strings.FieldsFunc(list, func(c rune) bool {
+		return c == ',' || c == ' '
+	})
+	for _, field := range fields {
+		from, to, isRange := strings.Cut(field, "-")
+		if !isRange {
+			to = from
+		}
+		low, err1 := strconv.Atoi(from)
+		high, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil || low > high {
+			return nil, fmt.Errorf("%q is not a number or range", field)
+		}
+		if low < 1 || high > max {
+			return nil, fmt.Errorf("%s is out of range; the branches are numbered 1 to %d", field, max)
+		}
+		for n := low; n <= high; n++ {
+			numbers[n] = true
+		}
+	}
+	return numbers, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNumberList(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []int
+		ok   bool
+	}{
+		{"2", []int{2}, true},
+		{"2, 5-7", []int{2, 5, 6, 7}, true},
+		{"1 3,3", []int{1, 3}, true},
+		{"4-4", []int{4}, true},
+		{"", nil, true},
+		{"7-5", nil, false},
+		{"0", nil, false},
+		{"9-11", nil, false},
+		{"two", nil, false},
+		{"1-", nil, false},
+	}
+	for _, tt := range tests {
+		got, err := parseNumberList(tt.in, 10)
+		if (err == nil) != tt.ok {
+			t.Errorf("parseNumberList(%q) error = %v, want ok %v", tt.in, err, tt.ok)
+			continue
+		}
+		want := make(map[int]bool)
+		for _, n := range tt.want {
+			want[n] = true
+		}
+		if tt.ok && !reflect.DeepEqual(got, want) {
+			t.Errorf("parseNumberList(%q) = %v, want %v", tt.in, got, want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+var (
+	stdin = bufio.NewReader(os.Stdin)
+
+	// stdinConsumed is set once stdin has been read as input data, so that
+	// answers to prompts must come from the terminal instead.
+	stdinConsumed bool
+
+	tty       *os.File
+	ttyReader *bufio.Reader
+	ttyOpened bool
+)
+
+// isTerminal reports whether f is connected to a terminal.
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// openTTY opens the controlling terminal once, returning false if there is
+// none, e.g. under cron.
+func openTTY() bool {
+	if !ttyOpened {
+		ttyOpened = true
+		if f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+			tty = f
+			ttyReader = bufio.NewReader(f)
+		}
+	}
+	return tty != nil
+}
+
+// ask shows prompt and reads a one-line answer. When stdin carried input data
+// or stdout is redirected, the exchange happens on the controlling terminal
+// so that confirmations still reach the user inside pipelines.
+func ask(prompt string) (string, error) {
+	if (stdinConsumed || !isTerminal(os.Stdout)) && openTTY() {
+		fmt.Fprintln(tty, prompt)
+		return readLine(ttyReader)
+	}
+	warn(prompt)
+	return readLine(stdin)
+}
+
+// askSecret is ask for tokens and passwords: the answer is not echoed where
+// the terminal allows turning echo off.
+func askSecret(prompt string) (string, error) {
+	if runtime.GOOS == "windows" || !openTTY() {
+		return ask(prompt)
+	}
+	_, _ = stty("-echo")
+	defer stty("echo")
+	fmt.Fprint(tty, prompt+" ")
+	answer, err := readLine(ttyReader)
+	fmt.Fprintln(tty)
+	return answer, err
+}
+
+// stty runs stty on the controlling terminal with args and returns its
+// output.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+// readLine reads one line of user input without its line ending.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,47 @@
+package main
+
+// protectedConfigKey is the multi-valued git config key listing branch
+// patterns that must never be deleted.
+const protectedConfigKey = AppName + ".protected"
+
+// protectedPatterns returns the protected branch patterns from the config
+// files and git config.
+func protectedPatterns() []string {
+	return config().Protected
+}
+
+func isProtected(branch string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matchesPattern(branch, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// protectBranch records pattern as protected in the repository's git config.
+func protectBranch(pattern string) error {
+	if contains(protectedPatterns(), pattern) {
+		return nil
+	}
+	if err := gitQuiet("config", "--add", protectedConfigKey, pattern); err != nil {
+		return err
+	}
+	config().Protected = append(config().Protected, pattern)
+	return nil
+}
+
+// filterProtectedBranches removes protected branches from a deletion.
+func filterProtectedBranches(branches []string) []string {
+	patterns := protectedPatterns()
+
+	var filtered []string
+	for _, branch := range branches {
+		if isProtected(branch, patterns) {
+			status("Protected branch (%s) cannot be deleted.", branch)
+		} else {
+			filtered = append(filtered, branch)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pruneRun records the candidates shown by a prune-remote dry run. --apply
+// only deletes branches if the candidates still match it.
+type pruneRun struct {
+	Remote    string            `json:"remote"`
+	OlderThan string            `json:"older_than"`
+	Created   time.Time         `json:"created"`
+	Branches  map[string]string `json:"branches"`
+}
+
+// pruneRunMaxAge is how long a dry run stays valid for --apply.
+const pruneRunMaxAge = 24 * time.Hour
+
+func runPruneRemote(args []string) {
+	apply, args := extractFlag(args, "--apply")
+	olderThan, ok, args := extractOption(args, "--older-than")
+	if !ok || len(args) != 0 {
+		usageError("prune-remote")
+	}
+	age, err := parseAge(olderThan)
+	if err != nil {
+		fatal(err)
+	}
+	if !remoteExists(remoteName) {
+		fatalf("Unknown remote %s", remoteName)
+	}
+
+	title("Fetching %s", remoteName)
+	if err := fetchPruned(remoteName); err != nil {
+		warn("Error fetching %s, nothing was deleted.", remoteName)
+		exit(1)
+	}
+
+	candidates, err := pruneCandidates(remoteName, age)
+	if err != nil {
+		warn("Error listing branches on %s: %s", remoteName, err)
+		exit(1)
+	}
+	names := make([]string, 0, len(candidates))
+	for name := range candidates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if !apply {
+		showPruneDryRun(names, olderThan)
+		if len(names) > 0 {
+			run := pruneRun{Remote: remoteName, OlderThan: olderThan, Created: time.Now(), Branches: candidates}
+			if err := savePruneRun(run); err != nil {
+				warn("Error saving dry run: %s", err)
+				exit(1)
+			}
+			status("\nDry run only. Run '%s prune-remote --older-than %s --remote %s --apply' to delete these branches.", AppName, olderThan, remoteName)
+		}
+		return
+	}
+
+	if len(names) == 0 {
+		status("No branches on %s are older than %s.", remoteName, olderThan)
+		return
+	}
+	if err := checkPruneRun(remoteName, olderThan, candidates); err != nil {
+		warn("Nothing was deleted: %s.", err)
+		warn("Run '%s prune-remote --older-than %s --remote %s' without --apply to review them first.", AppName, olderThan, remoteName)
+		exit(1)
+	}
+
+	if len(names) == 1 {
+		title("The following branch will be deleted from %s:", remoteName)
+	} else {
+		title("The following %d branches will be deleted from %s:", len(names), remoteName)
+	}
+	for _, name := range names {
+		info(name)
+	}
+	if !confirmDeletion() {
+		return
+	}
+	deleteRemoteBranches(remoteName, names)
+	clearPruneRun()
+}
+
+// fetchPruned fetches remote, dropping the remote-tracking branches of
+// branches deleted there.
+func fetchPruned(remote string) error {
+	if noGitBinary {
+		if err := goGitFetch(remote); err != nil {
+			warn("%s", err)
+			return err
+		}
+		return nil
+	}
+	return gitRun("fetch", "--prune", remote)
+}
+
+// pruneCandidates returns the branches on remote, with their tips, whose
+// last commit is older than age, leaving out protected branches, base
+// branches and branches with open pull requests.
+func pruneCandidates(remote string, age time.Duration) (map[string]string, error) {
+	var lines []string
+	var err error
+	if noGitBinary {
+		lines, err = goGitRemoteTrackingRefs(remote)
+	} else {
+		lines, err = gitLines("for-each-ref", "--format=%(refname) %(objectname) %(committerdate:unix)", "refs/remotes/"+remote+"/")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	prs, err := openPullRequests()
+	if err != nil {
+		return nil, fmt.Errorf("cannot check for open pull requests: %w", err)
+	}
+	if pullRequestProvider() == "" {
+		warn("No hosting provider is configured, so branches with open pull requests cannot be excluded.")
+	}
+
+	patterns := protectedPatterns()
+	var keep []string
+	for _, base := range baseBranches() {
+		keep = append(keep, strings.TrimPrefix(base, remote+"/"))
+	}
+
+	candidates := make(map[string]string)
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected for-each-ref output %q", line)
+		}
+		name := strings.TrimPrefix(fields[0], "refs/remotes/"+remote+"/")
+		seconds, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected for-each-ref output %q", line)
+		}
+
+		switch {
+		case name == "HEAD":
+		case time.Since(time.Unix(seconds, 0)) < age:
+		case isProtected(name, patterns):
+		case contains(keep, name):
+		case prs[name] != 0:
+		default:
+			candidates[name] = fields[1]
+		}
+	}
+	return candidates, nil
+}
+
+func showPruneDryRun(names []string, olderThan string) {
+	if len(names) == 0 {
+		status("No branches on %s are older than %s.", remoteName, olderThan)
+		return
+	}
+	branchStr := "branches on %s have"
+	if len(names) == 1 {
+		branchStr = "branch on %s has"
+	}
+	title("%d "+branchStr+" no commits for %s and would be deleted:", len(names), remoteName, olderThan)
+	for i, name := range names {
+		if plainOutput {
+			info("branch %d of %d: %s", i+1, len(names), name)
+		} else {
+			info(name)
+		}
+	}
+}
+
+func pruneRunPath() (string, error) {
+	common, err := gitCommonDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(common, AppName+"-prune-remote.json"), nil
+}
+
+func savePruneRun(run pruneRun) error {
+	path, err := pruneRunPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func clearPruneRun() {
+	if path, err := pruneRunPath(); err == nil {
+		os.Remove(path)
+	}
+}
+
+// checkPruneRun returns an error unless a recent dry run for the same remote
+// and age showed exactly candidates.
+func checkPruneRun(remote, olderThan string, candidates map[string]string) error {
+	path, err := pruneRunPath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return errors.New("prune-remote needs a dry run before --apply")
+	}
+	if err != nil {
+		return err
+	}
+	var run pruneRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return err
+	}
+
+	switch {
+	case run.Remote != remote || run.OlderThan != olderThan:
+		return fmt.Errorf("the last dry run was for --older-than %s on %s", run.OlderThan, run.Remote)
+	case time.Since(run.Created) > pruneRunMaxAge:
+		return fmt.Errorf("the last dry run is older than %s", formatDuration(pruneRunMaxAge))
+	case len(run.Branches) != len(candidates):
+		return errors.New("the branches to delete have changed since the dry run")
+	}
+	for name, sha := range candidates {
+		if run.Branches[name] != sha {
+			return errors.New("the branches to delete have changed since the dry run")
+		}
+	}
+	return nil
+}
+
+// lsRemoteBranches asks remote for its branches and their tips.
+func lsRemoteBranches(remote string) (map[string]string, error) {
+	if noGitBinary {
+		return goGitLsRemoteBranches(remote)
+	}
+	lines, err := gitLines("ls-remote", "--heads", remote)
+	if err != nil {
+		return nil, err
+	}
+	branches := make(map[string]string, len(lines))
+	for _, line := range lines {
+		sha, ref, ok := strings.Cut(line, "\t")
+		if !ok {
+			return nil, fmt.Errorf("unexpected ls-remote output %q", line)
+		}
+		branches[strings.TrimPrefix(ref, "refs/heads/")] = sha
+	}
+	return branches, nil
+}
+
+// deleteRemoteBranches deletes branches from remote, a chunk per push,
+// keeping a checkpoint so an interrupted run can be resumed.
+func deleteRemoteBranches(remote string, branches []string) {
+	deleted := 0
+	failed := make(map[string]string)
+	processWithCheckpoint(checkpoint{Operation: "remote-delete", Remote: remote}, chunkArgs(branches, maxArgBytes), func(chunk []string) {
+		var output []byte
+		var results map[string]string
+		if noGitBinary {
+			results = goGitDeleteRemoteBranches(remote, chunk)
+		} else {
+			args := append([]string{"push", "--porcelain", remote, "--delete"}, chunk...)
+			output, _ = gitCombinedOutput(args...)
+			results = parsePushPorcelain(string(output))
+		}
+		for _, branch := range chunk {
+			result, ok := results[branch]
+			switch {
+			case ok && result == "":
+				info("Deleted %s from %s", branch, remote)
+				deleted++
+			case ok:
+				failed[branch] = result
+			default:
+				failed[branch] = strings.TrimSpace(string(output))
+			}
+		}
+	})
+
+	for _, branch := range branches {
+		if errMsg, ok := failed[branch]; ok {
+			warn("Error deleting %s from %s: %s", branch, remote, errMsg)
+		}
+	}
+	status("\n%d out of %d branches deleted from %s.", deleted, len(branches), remote)
+}
+
+// parsePushPorcelain reads `git push --porcelain` output, mapping each
+// branch to "" if it was updated or to git's reason if it was rejected.
+func parsePushPorcelain(output string) map[string]string {
+	results := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 || len(fields[0]) != 1 {
+			continue
+		}
+		_, dst, _ := strings.Cut(fields[1], ":")
+		branch := strings.TrimPrefix(dst, "refs/heads/")
+		if fields[0] == "!" {
+			results[branch] = fields[2]
+		} else {
+			results[branch] = ""
+		}
+	}
+	return results
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePushPorcelain(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   map[string]string
+	}{
+		{
+			name: "deleted and rejected",
+			output: "To /tmp/remote.git\n" +
+				"-\t:refs/heads/feature/a\t[deleted]\n" +
+				"!\t:refs/heads/main\t[remote rejected] (deletion of the current branch prohibited)\n" +
+				"Done\n",
+			want: map[string]string{
+				"feature/a": "",
+				"main":      "[remote rejected] (deletion of the current branch prohibited)",
+			},
+		},
+		{
+			name:   "already gone",
+			output: "To origin\n!\t:refs/heads/tmp/x\t[remote rejected] (remote ref does not exist)\nDone\n",
+			want:   map[string]string{"tmp/x": "[remote rejected] (remote ref does not exist)"},
+		},
+		{
+			name:   "no ref lines",
+			output: "error: failed to push some refs\n",
+			want:   map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		if got := parsePushPorcelain(tt.output); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parsePushPorcelain() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
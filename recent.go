@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultRecentCount = 10
+	// reflogScanLimit bounds how far back the HEAD reflog is read.
+	reflogScanLimit = 1000
+)
+
+func runRecent(args []string) {
+	count := defaultRecentCount
+	if n, ok, rest := extractOption(args, "-n"); ok {
+		var err error
+		if count, err = strconv.Atoi(n); err != nil || count < 1 {
+			fatalf("Invalid -n %q: must be a positive number", n)
+		}
+		args = rest
+	}
+	if len(args) != 0 {
+		usageError("recent")
+	}
+
+	branches, err := recentBranches(count)
+	if err == nil && len(branches) == 0 {
+		status("No other branches have been checked out recently.")
+		return
+	}
+	var infos map[string]branchInfo
+	if err == nil {
+		infos, err = loadBranchInfos(branches)
+	}
+	if err != nil {
+		warn("Error reading recent branches: %s", err)
+		exit(1)
+	}
+
+	title("Recently checked out branches")
+	for i, branch := range branches {
+		if plainOutput {
+			info("recent branch h%d of %d: %s", i+1, len(branches), branch)
+		} else {
+			info("h%-3d %s  (%s)", i+1, branch, formatWhen(infos[branch].Date))
+		}
+	}
+}
+
+// recentBranches returns up to limit existing branches, most recently
+// checked out first, leaving out the current branch. Their positions define
+// the hN indexes.
+func recentBranches(limit int) ([]string, error) {
+	subjects, err := gitLines("reflog", "show", "--format=%gs", "-n", strconv.Itoa(reflogScanLimit), "HEAD", "--")
+	if err != nil {
+		return nil, err
+	}
+	branches, current, err := listBranches()
+	if err != nil {
+		return nil, err
+	}
+
+	var recent []string
+	for _, subject := range subjects {
+		move, ok := strings.CutPrefix(subject, "checkout: moving from ")
+		if !ok {
+			continue
+		}
+		from, _, ok := strings.Cut(move, " to ")
+		if !ok || from == current || contains(recent, from) || !contains(branches, from) {
+			continue
+		}
+		recent = append(recent, from)
+		if len(recent) == limit {
+			break
+		}
+	}
+	return recent, nil
+}
+
+// resolveRecentIndex maps an index such as h2 to a recently used branch.
+func resolveRecentIndex(arg string) (string, error) {
+	index, err := strconv.Atoi(strings.TrimPrefix(arg, "h"))
+	if err != nil {
+		return "", fmt.Errorf("%s is not a recent branch index", arg)
+	}
+	recent, err := recentBranches(index)
+	if err != nil {
+		return "", err
+	}
+	if index < 1 || index > len(recent) {
+		return "", fmt.Errorf("index %s is out of range; run '%s recent' to see recent branch indexes", arg, AppName)
+	}
+	return recent[index-1], nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// writeRecoveryScript appends a shell script to path that recreates each of
+// branches at its current tip, so a deletion can be undone without this tool.
+func writeRecoveryScript(path string, branches []string) error {
+	tips, err := branchTips()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o755)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var script strings.Builder
+	if stat, err := file.Stat(); err == nil && stat.Size() == 0 {
+		script.WriteString("#!/bin/sh\n")
+	}
+	fmt.Fprintf(&script, "\n# Branches deleted by %s on %s\n", AppName, time.Now().Format(time.RFC3339))
+	for _, branch := range branches {
+		sha, ok := tips[branch]
+		if !ok {
+			return fmt.Errorf("cannot resolve tip of %s", branch)
+		}
+		fmt.Fprintf(&script, "git branch %s %s\n", shellQuote(branch), sha)
+	}
+
+	if _, err := file.WriteString(script.String()); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// shellQuote quotes s for safe use as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
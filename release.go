@@ -0,0 +1,58 @@
+package main
+
+func runRelease(args []string) {
+	push, args := extractFlag(args, "--push")
+	noTag, args := extractFlag(args, "--no-tag")
+	if len(args) != 2 || args[0] != "cut" {
+		usageError("release")
+	}
+	cutRelease(args[1], push, !noTag)
+}
+
+// cutRelease creates release/<version> from the default branch, tags the
+// branch point, marks the branch protected and optionally pushes both.
+func cutRelease(version string, push, tag bool) {
+	base := baseBranch()
+	branch := "release/" + version
+	tagName := "release-" + version + "-branchpoint"
+
+	if branchExists(branch) {
+		warn("Branch %s already exists.", branch)
+		exit(1)
+	}
+
+	title("Cutting %s from %s", branch, base)
+	if output, err := gitCombinedOutput("branch", branch, base); err != nil {
+		warn("Error creating branch %s: %s", branch, output)
+		exit(1)
+	}
+	info("Created branch %s", branch)
+
+	if tag {
+		if output, err := gitCombinedOutput("tag", "-a", tagName, "-m", "Branch point of "+branch, branch); err != nil {
+			warn("Error creating tag %s: %s", tagName, output)
+			exit(1)
+		}
+		info("Tagged branch point as %s", tagName)
+	}
+
+	if err := protectBranch(branch); err != nil {
+		warn("Error marking %s as protected: %s", branch, err)
+		exit(1)
+	}
+	info("Marked %s as protected", branch)
+
+	if push {
+		refs := []string{"push", "-u", remoteName, branch}
+		if tag {
+			refs = append(refs, tagName)
+		}
+		if output, err := gitCombinedOutput(refs...); err != nil {
+			warn("Error pushing to %s: %s", remoteName, output)
+			exit(1)
+		}
+		info("Pushed to %s", remoteName)
+	}
+
+	status("Release branch %s is ready.", branch)
+}
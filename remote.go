@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// remoteBranch is a branch on the remote as last fetched.
+type remoteBranch struct {
+	name   string
+	date   time.Time
+	author string
+}
+
+// runRemote lists or deletes the branches on the remote, as seen through
+// its remote-tracking refs, using the same patterns as the local commands.
+func runRemote(args []string) {
+	if len(args) == 0 {
+		usageError("remote")
+	}
+	switch args[0] {
+	case "list":
+		if len(args) > 2 {
+			usageError("remote")
+		}
+		pattern := ""
+		if len(args) == 2 {
+			pattern = args[1]
+		}
+		showRemoteBranches(pattern)
+	case "delete":
+		if len(args) != 2 {
+			usageError("remote")
+		}
+		deleteSelectedRemoteBranches(func(name string) bool { return matchesPattern(name, args[1]) })
+	case "keep":
+		if len(args) < 2 {
+			usageError("remote")
+		}
+		patterns := args[1:]
+		deleteSelectedRemoteBranches(func(name string) bool {
+			for _, pattern := range patterns {
+				if matchesPattern(name, pattern) {
+					return false
+				}
+			}
+			return true
+		})
+	default:
+		usageError("remote")
+	}
+}
+
+// remoteBranches reads the remote's branches from its remote-tracking refs,
+// oldest commit first.
+func remoteBranches(remote string) ([]remoteBranch, error) {
+	lines, err := gitLines("for-each-ref", "--sort=committerdate",
+		"--format=%(refname)%00%(committerdate:unix)%00%(authorname)", "refs/remotes/"+remote+"/")
+	if err != nil {
+		return nil, err
+	}
+	var branches []remoteBranch
+	for _, line := range lines {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected for-each-ref output %q", line)
+		}
+		name := strings.TrimPrefix(fields[0], "refs/remotes/"+remote+"/")
+		if name == "HEAD" {
+			continue
+		}
+		seconds, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected for-each-ref output %q", line)
+		}
+		branches = append(branches, remoteBranch{name: name, date: time.Unix(seconds, 0), author: fields[2]})
+	}
+	return branches, nil
+}
+
+func showRemoteBranches(pattern string) {
+	branches, err := remoteBranches(remoteName)
+	if err != nil {
+		warn("Error listing branches on %s: %s", remoteName, err)
+		exit(1)
+	}
+	warnIfFetchIsStale()
+
+	var shown []remoteBranch
+	for _, b := range branches {
+		if pattern == "" || matchesPattern(b.name, pattern) {
+			shown = append(shown, b)
+		}
+	}
+	if len(shown) == 0 {
+		status("No branches on %s match.", remoteName)
+		return
+	}
+
+	patterns := protectedPatterns()
+	bases := remoteBaseBranches()
+	title("Branches on %s", remoteName)
+	t := table{}
+	for i, b := range shown {
+		note := ""
+		if isProtected(b.name, patterns) || contains(bases, b.name) {
+			note = "protected"
+		}
+		if plainOutput {
+			if note != "" {
+				note = ", " + note
+			}
+			info("branch %d of %d: %s, last commit %s by %s%s", i+1, len(shown), b.name, formatWhen(b.date), b.author, note)
+		} else {
+			t.addRow(b.name, formatWhen(b.date), b.author, note)
+		}
+	}
+	for _, line := range t.lines() {
+		info("%s", line)
+	}
+}
+
+// remoteBaseBranches returns the base branches as names on the remote.
+func remoteBaseBranches() []string {
+	var bases []string
+	for _, base := range baseBranches() {
+		bases = append(bases, strings.TrimPrefix(base, remoteName+"/"))
+	}
+	return bases
+}
+
+// deleteSelectedRemoteBranches deletes, after confirmation, the branches on
+// the remote that selected picks, never touching protected or base branches.
+func deleteSelectedRemoteBranches(selected func(name string) bool) {
+	branches, err := remoteBranches(remoteName)
+	if err != nil {
+		warn("Error listing branches on %s: %s", remoteName, err)
+		exit(1)
+	}
+	warnIfFetchIsStale()
+
+	patterns := protectedPatterns()
+	bases := remoteBaseBranches()
+	var toDelete, skipped []string
+	for _, b := range branches {
+		switch {
+		case !selected(b.name):
+		case isProtected(b.name, patterns) || contains(bases, b.name):
+			skipped = append(skipped, b.name)
+		default:
+			toDelete = append(toDelete, b.name)
+		}
+	}
+	if len(skipped) > 0 {
+		info("Skipping protected branches: %s", strings.Join(skipped, ", "))
+	}
+	if len(toDelete) == 0 {
+		status("No branches on %s to delete.", remoteName)
+		return
+	}
+
+	if len(toDelete) == 1 {
+		title("The following branch will be deleted from %s:", remoteName)
+	} else {
+		title("The following %d branches will be deleted from %s:", len(toDelete), remoteName)
+	}
+	for i, name := range toDelete {
+		if plainOutput {
+			info("branch %d of %d: %s", i+1, len(toDelete), name)
+		} else {
+			info(name)
+		}
+	}
+	if !confirmDeletion() {
+		return
+	}
+	deleteRemoteBranches(remoteName, toDelete)
+}
@@ -0,0 +1,28 @@
+package main
+
+// runRename renames a local branch, refusing to rename protected branches
+// since their names are what protects them.
+func runRename(args []string) {
+	if len(args) != 2 {
+		usageError("rename")
+	}
+
+	branch, err := resolveBranchArg(args[0])
+	if err != nil {
+		warn(err.Error())
+		exit(1)
+	}
+	if isProtected(branch, protectedPatterns()) {
+		warn("Protected branch (%s) cannot be renamed.", branch)
+		exit(1)
+	}
+
+	if dryRun {
+		status("Dry run, %s was not renamed to %s.", branch, args[1])
+		return
+	}
+	if err := gitRun("branch", "-m", branch, args[1]); err != nil {
+		exit(1)
+	}
+	status("Renamed %s to %s.", branch, args[1])
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReportConfig controls `report`. Only the global config and git config can
+// set the mail server.
+type ReportConfig struct {
+	SMTP SMTPConfig `yaml:"smtp"`
+}
+
+// SMTPConfig is the mail server reports are sent through. The password is
+// read from the <APP>_SMTP_PASSWORD environment variable or the keychain,
+// see `auth login smtp`, rather than from a config file.
+type SMTPConfig struct {
+	Host string `yaml:"host"`
+	// Port defaults to 587, with STARTTLS. Port 465 uses TLS from the
+	// start.
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	// From is the sender address. Empty means the username.
+	From string `yaml:"from"`
+}
+
+// hygieneReport is the state of a repository's branches that `report`
+// renders.
+type hygieneReport struct {
+	repository  string
+	time        time.Time
+	total       int
+	merged      []branchInfo
+	gone        []branchInfo
+	stale       []branchInfo
+	violations  []policyViolation
+	suggestions []suggestion
+}
+
+func runReport(args []string) {
+	asHTML, args := extractFlag(args, "--html")
+	recipients, args := extractOptions(args, "--email")
+	if len(args) != 0 {
+		usageError("report")
+	}
+
+	report, err := buildHygieneReport()
+	if err != nil {
+		warn("Error analyzing branches: %s", err)
+		exit(1)
+	}
+	if len(recipients) == 0 {
+		if asHTML {
+			fmt.Print(report.html())
+		} else {
+			fmt.Print(report.markdown())
+		}
+		return
+	}
+
+	if err := sendReport(report, recipients); err != nil {
+		warn("Error sending the report: %s", err)
+		exit(1)
+	}
+	status("Sent the report to %s.", strings.Join(recipients, ", "))
+}
+
+// buildHygieneReport sorts the branches that could be deleted into merged,
+// gone and stale, as suggest does, and adds the policy violations.
+func buildHygieneReport() (hygieneReport, error) {
+	report := hygieneReport{time: time.Now()}
+	if lines, err := gitLines("rev-parse", "--show-toplevel"); err == nil && len(lines) > 0 {
+		report.repository = filepath.Base(lines[0])
+	}
+
+	branches, current, err := listBranches()
+	if err != nil {
+		return report, err
+	}
+	report.total = len(branches)
+	sortBranches(branches)
+	candidates, _ := partitionDeletable(branches, current, false)
+
+	infos, err := loadBranchInfos(candidates)
+	if err != nil {
+		return report, err
+	}
+	gone, err := goneBranches()
+	if err != nil {
+		return report, err
+	}
+	for _, branch := range candidates {
+		b := infos[branch]
+		switch {
+		case b.Merged:
+			report.merged = append(report.merged, b)
+		case contains(gone, branch):
+			report.gone = append(report.gone, b)
+		case time.Since(b.Date) >= staleAfter:
+			report.stale = append(report.stale, b)
+		}
+	}
+
+	if report.violations, err = evaluatePolicies(); err != nil {
+		return report, err
+	}
+	if report.suggestions, err = cleanupSuggestions(); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+func (r hygieneReport) subject() string {
+	return fmt.Sprintf("Branch hygiene report for %s, %s", r.repository, r.time.Format("2006-01-02"))
+}
+
+// sections are the branch groups of the report with their headings.
+func (r hygieneReport) sections() []struct {
+	heading  string
+	branches []branchInfo
+} {
+	return []struct {
+		heading  string
+		branches []branchInfo
+	}{
+		{"Merged into " + strings.Join(baseBranches(), " or "), r.merged},
+		{"Upstream deleted, not merged", r.gone},
+		{"No commits for " + formatDuration(staleAfter) + ", not merged", r.stale},
+	}
+}
+
+func (r hygieneReport) summary() string {
+	return fmt.Sprintf("%s: %d merged, %d with their upstream deleted, %d stale; %s.",
+		branchCount(r.total), len(r.merged), len(r.gone), len(r.stale), plural(len(r.violations), "policy violation"))
+}
+
+// markdown renders the report as Markdown, which also reads well as plain
+// text.
+func (r hygieneReport) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n%s\n", r.subject(), r.summary())
+	for _, s := range r.sections() {
+		if len(s.branches) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n| Branch | Author | Last commit |\n| --- | --- | --- |\n", s.heading)
+		for _, br := range s.branches {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownCell(br.Name), markdownCell(br.Author), br.Date.Format("2006-01-02"))
+		}
+	}
+	if len(r.violations) > 0 {
+		b.WriteString("\n## Policy violations\n\n")
+		for _, v := range r.violations {
+			fmt.Fprintf(&b, "- %s: %s\n", v.Severity, v.Message)
+		}
+	}
+	if len(r.suggestions) > 0 {
+		b.WriteString("\n## Suggestions\n\n")
+		for _, s := range r.suggestions {
+			fmt.Fprintf(&b, "- %s: `%s %s`\n", s.reason, AppName, s.command)
+		}
+	}
+	return b.String()
+}
+
+func markdownCell(text string) string {
+	return strings.ReplaceAll(text, "|", `\|`)
+}
+
+// html renders the report as a standalone HTML document for email.
+func (r hygieneReport) html() string {
+	e := html.EscapeString
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>%s</title></head>\n<body>\n", e(r.subject()))
+	fmt.Fprintf(&b, "<h1>%s</h1>\n<p>%s</p>\n", e(r.subject()), e(r.summary()))
+	for _, s := range r.sections() {
+		if len(s.branches) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "<h2>%s</h2>\n<table>\n<tr><th align=\"left\">Branch</th><th align=\"left\">Author</th><th align=\"left\">Last commit</th></tr>\n", e(s.heading))
+		for _, br := range s.branches {
+			fmt.Fprintf(&b, "<tr><td>%s</td><td>%s</td><td>%s</td></tr>\n", e(br.Name), e(br.Author), br.Date.Format("2006-01-02"))
+		}
+		b.WriteString("</table>\n")
+	}
+	if len(r.violations) > 0 {
+		b.WriteString("<h2>Policy violations</h2>\n<ul>\n")
+		for _, v := range r.violations {
+			fmt.Fprintf(&b, "<li>%s: %s</li>\n", e(v.Severity), e(v.Message))
+		}
+		b.WriteString("</ul>\n")
+	}
+	if len(r.suggestions) > 0 {
+		b.WriteString("<h2>Suggestions</h2>\n<ul>\n")
+		for _, s := range r.suggestions {
+			fmt.Fprintf(&b, "<li>%s: <code>%s %s</code></li>\n", e(s.reason), AppName, e(s.command))
+		}
+		b.WriteString("</ul>\n")
+	}
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
+
+// sendReport mails the report to recipients as Markdown text with an HTML
+// alternative.
+func sendReport(report hygieneReport, recipients []string) error {
+	cfg := config().Report.SMTP
+	if cfg.Host == "" {
+		return fmt.Errorf("report.smtp.host is not set")
+	}
+	from := cfg.From
+	if from == "" {
+		from = cfg.Username
+	}
+	if from == "" {
+		return fmt.Errorf("report.smtp.from is not set")
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+
+	message, err := reportMessage(report, from, recipients)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		password, _ := smtpPassword(cfg.Host)
+		auth = smtp.PlainAuth("", cfg.Username, password, cfg.Host)
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	if port != 465 {
+		// SendMail switches to TLS with STARTTLS when the server offers it.
+		return smtp.SendMail(addr, auth, from, recipients, message)
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second}, "tcp", addr, &tls.Config{ServerName: cfg.Host})
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, to := range recipients {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// reportMessage builds the email holding the report.
+func reportMessage(report hygieneReport, from string, recipients []string) ([]byte, error) {
+	var body strings.Builder
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, text string }{
+		{"text/plain; charset=utf-8", report.markdown()},
+		{"text/html; charset=utf-8", report.html()},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.text)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", report.subject()))
+	fmt.Fprintf(&msg, "Date: %s\r\n", report.time.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+	msg.WriteString(body.String())
+	return []byte(msg.String()), nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import "os"
+
+// checkRepository makes sure there is a repository with branches for c to
+// work on, explaining what to do instead of letting the first git command
+// fail with a bare exit status. Commands that work through go-git only need
+// the repository.
+func checkRepository(c command) {
+	missing := gitBinaryMissing()
+	if missing && !c.goGit {
+		warn("git is not installed or not on your PATH. Install git to use '%s %s'.", AppName, c.name)
+		exit(1)
+	}
+
+	var err error
+	if missing {
+		_, err = openGoGitRepository()
+	} else {
+		_, err = gitLines("rev-parse", "--git-dir")
+	}
+	if err != nil {
+		dir, _ := os.Getwd()
+		warn("%s is not inside a git repository (or any of its parent directories).", dir)
+		info("Change to a repository's directory, or create one with 'git init' or 'git clone <url>'.")
+		if ceiling := os.Getenv("GIT_CEILING_DIRECTORIES"); ceiling != "" {
+			info("GIT_CEILING_DIRECTORIES is set to %s, which stops git looking in the directories above it.", ceiling)
+		}
+		exit(1)
+	}
+	if missing {
+		return
+	}
+
+	if branches, err := gitLines("for-each-ref", "--count=1", "refs/heads"); err == nil && len(branches) == 0 {
+		head := "HEAD"
+		if lines, err := gitLines("symbolic-ref", "--short", "HEAD"); err == nil && len(lines) > 0 {
+			head = lines[0]
+		}
+		status("This repository has no branches yet: %s has no commits.", head)
+		info("Make the first commit, for example with git commit --allow-empty -m 'Initial commit', and %s will have branches to manage.", AppName)
+		exit(0)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+)
+
+// reviewBranches walks through branches one at a time, asking whether each
+// should be kept or deleted, and returns the branches chosen for deletion.
+func reviewBranches(branches []string) []string {
+	infos, err := loadBranchInfos(branches)
+	if err != nil {
+		warn("Error reading branch details: %s", err)
+		exit(1)
+	}
+
+	title("Reviewing %d matching branches (k = keep, d = delete, q = keep the rest):", len(branches))
+
+	var toDelete []string
+	for i, branch := range branches {
+		b := infos[branch]
+		info("\n[%d/%d] %s", i+1, len(branches), branch)
+		info("last commit %s by %s, %s", formatWhen(b.Date), b.Author, b.mergedLabel())
+
+	prompt:
+		for {
+			answer, err := ask("Keep or delete? [k/d/q]")
+			if err != nil {
+				return toDelete
+			}
+			switch strings.ToLower(answer) {
+			case "k", "keep":
+				break prompt
+			case "d", "delete":
+				toDelete = append(toDelete, branch)
+				break prompt
+			case "q", "quit":
+				return toDelete
+			}
+		}
+	}
+	return toDelete
+}
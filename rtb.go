@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+)
+
+func runRemoteTrackingBranches(args []string) {
+	if len(args) != 2 || args[0] != "delete" {
+		usageError("rtb")
+	}
+	deleteRemoteTrackingBranches(args[1])
+}
+
+// listRemoteTrackingBranches returns the short names (remote/branch) of the
+// local remote-tracking refs, leaving out symbolic remote HEADs.
+func listRemoteTrackingBranches() ([]string, error) {
+	refs, err := gitLines("for-each-ref", "--format=%(refname:short)", "refs/remotes")
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []string
+	for _, ref := range refs {
+		if !strings.HasSuffix(ref, "/HEAD") && strings.Contains(ref, "/") {
+			branches = append(branches, ref)
+		}
+	}
+	return branches, nil
+}
+
+// deleteRemoteTrackingBranches removes the remote-tracking refs matching
+// pattern from the local repository without contacting the remote.
+func deleteRemoteTrackingBranches(pattern string) {
+	refs, err := listRemoteTrackingBranches()
+	if err != nil {
+		warn("Error listing remote-tracking branches: %s", err)
+		exit(1)
+	}
+
+	var toDelete []string
+	for _, ref := range refs {
+		if matchesPattern(ref, pattern) {
+			toDelete = append(toDelete, ref)
+		}
+	}
+
+	if len(toDelete) == 0 {
+		status("No remote-tracking branches match the given pattern.")
+		return
+	}
+
+	if len(toDelete) == 1 {
+		title("The following remote-tracking branch will be removed locally:")
+	} else {
+		title("The following %d remote-tracking branches will be removed locally:", len(toDelete))
+	}
+	for i, ref := range toDelete {
+		if plainOutput {
+			info("branch %d of %d: %s", i+1, len(toDelete), ref)
+		} else {
+			info(ref)
+		}
+	}
+	if !confirmDeletion() {
+		return
+	}
+	removeRemoteTrackingBranches(toDelete)
+}
+
+// removeRemoteTrackingBranches deletes the given remote-tracking refs,
+// keeping a checkpoint so an interrupted run can be resumed.
+func removeRemoteTrackingBranches(refs []string) {
+	var chunks [][]string
+	for _, ref := range refs {
+		chunks = append(chunks, []string{ref})
+	}
+
+	deleted := 0
+	processWithCheckpoint(checkpoint{Operation: "rtb-delete"}, chunks, func(chunk []string) {
+		ref := chunk[0]
+		if output, err := gitCombinedOutput("branch", "-r", "-d", ref); err != nil {
+			warn("Error deleting %s: %s", ref, output)
+			return
+		}
+		info("Deleted remote-tracking branch %s", ref)
+		deleted++
+	})
+	status("%d out of %d remote-tracking branches deleted.", deleted, len(refs))
+}
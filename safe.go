@@ -0,0 +1,27 @@
+package main
+
+import "strings"
+
+// isSafeInAnyBase reports whether branch has no commits missing from one of
+// bases, treating cherry-picked equivalents as present.
+func isSafeInAnyBase(branch string, bases []string) bool {
+	for _, base := range bases {
+		if isSafeToDelete(branch, base) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSafeToDelete(branch, base string) bool {
+	lines, err := gitLines("cherry", base, "refs/heads/"+branch)
+	if err != nil {
+		return false
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "+") {
+			return false
+		}
+	}
+	return true
+}
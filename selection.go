@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// selection describes which local branches a command acts on. A branch is
+// selected only if it satisfies every criterion that is set.
+type selection struct {
+	pattern string
+	// regex selects branches whose whole name it matches, for naming
+	// schemes wildcards cannot express.
+	regex *regexp.Regexp
+	// regexText is the --regex expression as given, for display.
+	regexText  string
+	ignoreCase bool
+	invert     bool
+	merged     bool
+	olderThan  time.Duration
+	newerThan  time.Duration
+	author     string
+	gone       bool
+	mine       bool
+	// labels selects branches with a label matching any of them,
+	// ignoring case.
+	labels []string
+	where  whereExpr
+	// whereText is the --where expression as given, for display.
+	whereText string
+}
+
+// parseSelection extracts the selection flags from args after expanding saved
+// @name filters. A pattern is left in the returned args for the caller to
+// interpret.
+func parseSelection(args []string) (selection, []string, error) {
+	var sel selection
+	args, err := expandNamedFilters(args)
+	if err != nil {
+		return sel, nil, err
+	}
+
+	var stale bool
+	sel.ignoreCase, args = extractFlag(args, "-i", "--ignore-case")
+	sel.invert, args = extractFlag(args, "--invert")
+	sel.merged, args = extractFlag(args, "--merged")
+	stale, args = extractFlag(args, "--stale")
+	sel.gone, args = extractFlag(args, "--gone")
+	sel.mine, args = extractFlag(args, "--mine")
+	sel.author, _, args = extractOption(args, "--author")
+	sel.labels, args = extractOptions(args, "--label")
+
+	if where, ok, rest := extractOption(args, "--where"); ok {
+		expr, err := parseWhere(where)
+		if err != nil {
+			return sel, nil, err
+		}
+		sel.where, sel.whereText, args = expr, where, rest
+	}
+
+	if re, ok, rest := extractOption(args, "--regex"); ok {
+		flags := ""
+		if sel.ignoreCase {
+			flags = "(?i)"
+		}
+		if _, err := regexp.Compile(re); err != nil {
+			return sel, nil, fmt.Errorf("invalid --regex: %w", err)
+		}
+		// Anchored so that the whole name must match, like a pattern.
+		sel.regex = regexp.MustCompile(flags + "^(?:" + re + ")$")
+		sel.regexText, args = re, rest
+	}
+
+	olderThan, ok, args := extractOption(args, "--older-than")
+	if ok {
+		d, err := parseAge(olderThan)
+		if err != nil {
+			return sel, nil, err
+		}
+		sel.olderThan = d
+	} else if stale {
+		sel.olderThan = staleAfter
+	}
+
+	newerThan, ok, args := extractOption(args, "--newer-than")
+	if ok {
+		d, err := parseAge(newerThan)
+		if err != nil {
+			return sel, nil, err
+		}
+		sel.newerThan = d
+	}
+	return sel, args, nil
+}
+
+// reasons describes the criteria of s, e.g. to explain why branches were
+// selected.
+func (s selection) reasons() []string {
+	var reasons []string
+	if s.pattern != "" {
+		if s.invert {
+			reasons = append(reasons, "does not match "+s.pattern)
+		} else {
+			reasons = append(reasons, "matches "+s.pattern)
+		}
+	}
+	if s.regex != nil {
+		if s.invert {
+			reasons = append(reasons, "does not match /"+s.regexText+"/")
+		} else {
+			reasons = append(reasons, "matches /"+s.regexText+"/")
+		}
+	}
+	if s.merged {
+		reasons = append(reasons, "merged into "+strings.Join(baseBranches(), " or "))
+	}
+	if s.olderThan > 0 {
+		reasons = append(reasons, "no commits for "+formatDuration(s.olderThan))
+	}
+	if s.newerThan > 0 {
+		reasons = append(reasons, "committed to in the last "+formatDuration(s.newerThan))
+	}
+	if s.author != "" {
+		reasons = append(reasons, "author matches "+s.author)
+	}
+	if s.gone {
+		reasons = append(reasons, "upstream is gone")
+	}
+	if s.mine {
+		reasons = append(reasons, "authored by you")
+	}
+	if len(s.labels) > 0 {
+		reasons = append(reasons, "labeled "+strings.Join(s.labels, " or "))
+	}
+	if s.where != nil {
+		reasons = append(reasons, "where "+s.whereText)
+	}
+	return reasons
+}
+
+// hasFilters reports whether any criterion other than the pattern is set.
+func (s selection) hasFilters() bool {
+	return s.regex != nil || s.merged || s.olderThan > 0 || s.newerThan > 0 || s.author != "" || s.gone || s.mine || len(s.labels) > 0 || s.where != nil
+}
+
+// filter returns the branches that satisfy every criterion of s, keeping
+// their order.
+func (s selection) filter(branches []string) ([]string, error) {
+	var merged, gone []string
+	var dates map[string]time.Time
+	var labeled map[string][]string
+	var err error
+	if s.merged {
+		if merged, err = mergedIntoAnyBase(); err != nil {
+			return nil, err
+		}
+		merged = withoutBasesAndCurrent(merged)
+	}
+	if s.gone {
+		if gone, err = goneBranches(); err != nil {
+			return nil, err
+		}
+	}
+	if s.olderThan > 0 || s.newerThan > 0 {
+		if dates, err = branchCommitDates(); err != nil {
+			return nil, err
+		}
+	}
+	if len(s.labels) > 0 {
+		labeled = branchLabels()
+	}
+
+	var selected []string
+	for _, branch := range branches {
+		switch {
+		case s.pattern != "" && matchesPatternCase(branch, s.pattern, s.ignoreCase) == s.invert:
+		case s.regex != nil && s.regex.MatchString(branch) == s.invert:
+		case s.merged && !contains(merged, branch):
+		case s.gone && !contains(gone, branch):
+		case s.olderThan > 0 && time.Since(dates[branch]) < s.olderThan:
+		case s.newerThan > 0 && time.Since(dates[branch]) >= s.newerThan:
+		case len(s.labels) > 0 && !hasAnyLabel(labeled[branch], s.labels):
+		default:
+			selected = append(selected, branch)
+		}
+	}
+
+	if s.author == "" && !s.mine && s.where == nil {
+		return selected, nil
+	}
+
+	// The remaining checks need a lookup per branch, so they run last on what
+	// is left.
+	infos, err := loadBranchInfos(selected)
+	if err != nil {
+		return nil, err
+	}
+	if s.where != nil && gone == nil {
+		if gone, err = goneBranches(); err != nil {
+			return nil, err
+		}
+	}
+	identities := myIdentities()
+	author := strings.ToLower(s.author)
+
+	var detailed []string
+	notMine := 0
+	for _, branch := range selected {
+		b := infos[branch]
+		if author != "" && !strings.Contains(strings.ToLower(b.Author), author) && !strings.Contains(strings.ToLower(b.Email), author) {
+			continue
+		}
+		if s.mine && !isMine(b, identities) {
+			notMine++
+			continue
+		}
+		if s.where != nil {
+			env := whereEnv{info: b, gone: contains(gone, branch), mine: isMine(b, identities)}
+			ok, err := evalBool(s.where, env)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		detailed = append(detailed, branch)
+	}
+	if notMine > 0 {
+		status("Skipped %s last committed by someone else (--mine).", branchCount(notMine))
+	}
+	return detailed, nil
+}
+
+// withoutBasesAndCurrent removes the base branches and the checked-out
+// branch from merged. Each of them is merged into a base, if only into
+// itself, but none is what --merged is after.
+func withoutBasesAndCurrent(merged []string) []string {
+	var skip []string
+	for _, base := range baseBranches() {
+		skip = append(skip, strings.TrimPrefix(base, remoteName+"/"))
+	}
+	if current, err := gitLines("symbolic-ref", "--short", "-q", "HEAD"); err == nil && len(current) > 0 {
+		skip = append(skip, current[0])
+	}
+	var kept []string
+	for _, branch := range merged {
+		if !contains(skip, branch) {
+			kept = append(kept, branch)
+		}
+	}
+	return kept
+}
+
+// goneBranches returns the local branches whose upstream branch no longer
+// exists on the remote.
+func goneBranches() ([]string, error) {
+	lines, err := gitLines("for-each-ref", "--format=%(refname:short)%00%(upstream:track)", "refs/heads")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read upstream state: %w", err)
+	}
+
+	var gone []string
+	for _, line := range lines {
+		branch, track, _ := strings.Cut(line, "\x00")
+		if track == "[gone]" {
+			gone = append(gone, branch)
+		}
+	}
+	return gone, nil
+}
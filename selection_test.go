@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSelectionRegex(t *testing.T) {
+	tests := []struct {
+		args    []string
+		matches []string
+		misses  []string
+	}{
+		{
+			args:    []string{"--regex", `feature/\d+`},
+			matches: []string{"feature/12"},
+			// The whole name must match.
+			misses: []string{"feature/12x", "my-feature/1", "Feature/1"},
+		},
+		{
+			args:    []string{"-i", "--regex", `feature/\d+`},
+			matches: []string{"feature/1", "Feature/1"},
+			misses:  []string{"feature/x"},
+		},
+		{
+			args:    []string{"--regex", `fix|hotfix/.*`},
+			matches: []string{"fix", "hotfix/login"},
+			misses:  []string{"fixes", "bugfix/1"},
+		},
+	}
+	for _, tt := range tests {
+		sel, rest, err := parseSelection(tt.args)
+		if err != nil || len(rest) != 0 {
+			t.Errorf("parseSelection(%q) = %q, %v", tt.args, rest, err)
+			continue
+		}
+		for _, name := range tt.matches {
+			if !sel.regex.MatchString(name) {
+				t.Errorf("%q does not match %s", name, tt.args)
+			}
+		}
+		for _, name := range tt.misses {
+			if sel.regex.MatchString(name) {
+				t.Errorf("%q matches %s", name, tt.args)
+			}
+		}
+	}
+}
+
+func TestParseSelectionErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"--regex", "feature/("},
+		{"--older-than", "soon"},
+		{"--newer-than", "-1d"},
+		{"--where", "age >"},
+		{"--where", `merged && age == "old"`},
+	} {
+		if _, _, err := parseSelection(args); err == nil {
+			t.Errorf("parseSelection(%q) succeeded", args)
+		}
+	}
+}
+
+func TestParseSelectionLeavesOtherArgs(t *testing.T) {
+	sel, rest, err := parseSelection([]string{"tmp/*", "--merged", "--force", "--older-than", "2w"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sel.merged || sel.olderThan != 14*24*time.Hour {
+		t.Errorf("selection = %+v", sel)
+	}
+	if want := []string{"tmp/*", "--force"}; !reflect.DeepEqual(rest, want) {
+		t.Errorf("rest = %q, want %q", rest, want)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strconv"
+)
+
+var (
+	shallowChecked bool
+	shallowRepo    bool
+	historyWarned  bool
+)
+
+// isShallowRepository reports whether the repository is a shallow clone,
+// whose history stops at the shallow boundary.
+func isShallowRepository() bool {
+	if !shallowChecked {
+		lines, err := gitLines("rev-parse", "--is-shallow-repository")
+		shallowRepo = err == nil && len(lines) > 0 && lines[0] == "true"
+		shallowChecked = true
+	}
+	return shallowRepo
+}
+
+// partialCloneRemote returns the remote that a partial clone fetches its
+// missing objects from, or "" if the repository is not a partial clone.
+// Partial clones have every commit, so merged and age results are reliable,
+// but checks that compare file contents download objects as they go.
+func partialCloneRemote() string {
+	lines, err := gitLines("config", "--get", "extensions.partialClone")
+	if err != nil || len(lines) == 0 {
+		return ""
+	}
+	return lines[0]
+}
+
+// warnIfShallow warns, once, that merged and age results may be wrong in a
+// shallow clone.
+func warnIfShallow() {
+	if historyWarned || !isShallowRepository() {
+		return
+	}
+	historyWarned = true
+	warn("This is a shallow clone, so merged and age results near the shallow boundary may be wrong. Branches whose history is cut off are shown as unknown rather than unmerged. Use --deepen n to fetch more history first.")
+}
+
+// sharesHistoryWithBase reports whether branch has a common ancestor with any
+// base branch. In a shallow clone a branch without one may still be merged;
+// the history that would show it is just missing.
+func sharesHistoryWithBase(branch string) bool {
+	for _, base := range baseBranches() {
+		if gitQuiet("merge-base", base, "refs/heads/"+branch) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// deepenHistory fetches depth more commits of history from the remote.
+func deepenHistory(depth string) {
+	if n, err := strconv.Atoi(depth); err != nil || n < 1 {
+		warn("--deepen needs a positive number of commits, not %q.", depth)
+		exit(1)
+	}
+	if !isShallowRepository() {
+		info("The repository is not shallow, so there is no history to deepen.")
+		return
+	}
+	title("Fetching %s more commits of history from %s", depth, remoteName)
+	if err := gitRun("fetch", "--deepen="+depth, remoteName); err != nil {
+		warn("Error deepening history from %s.", remoteName)
+		exit(1)
+	}
+	shallowChecked = false
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// cdFileEnv names the environment variable through which the shell wrapper
+// from shell-init asks for the directory to change to. A process cannot
+// change its parent's directory, so commands write the directory to that
+// file and the wrapper changes to it when the command exits.
+var cdFileEnv = strings.ToUpper(AppName) + "_CD_FILE"
+
+var shellInitScripts = map[string]string{
+	"bash": `{{app}}() {
+    local cd_file code
+    cd_file=$(mktemp) || return
+    {{env}}="$cd_file" command {{app}} "$@"
+    code=$?
+    if [ -s "$cd_file" ]; then
+        cd -- "$(cat "$cd_file")" || code=$?
+    fi
+    rm -f "$cd_file"
+    return $code
+}
+`,
+	"fish": `function {{app}}
+    set -l cd_file (mktemp); or return
+    env {{env}}=$cd_file {{app}} $argv
+    set -l code $status
+    if test -s $cd_file
+        cd (cat $cd_file); or set code $status
+    end
+    rm -f $cd_file
+    return $code
+end
+`,
+}
+
+// runShellInit prints the wrapper function for shell, which lets commands
+// such as switch --worktree change the shell's directory.
+func runShellInit(args []string) {
+	if len(args) != 1 {
+		usageError("shell-init")
+	}
+	shell := args[0]
+	if shell == "zsh" {
+		shell = "bash"
+	}
+	script, ok := shellInitScripts[shell]
+	if !ok {
+		usageError("shell-init")
+	}
+
+	fmt.Print(strings.NewReplacer(
+		"{{app}}", AppName,
+		"{{env}}", cdFileEnv,
+	).Replace(script))
+}
+
+// changeDirectory hands dir to the shell wrapper to change to, or tells the
+// user to change to it when the wrapper is not installed.
+func changeDirectory(dir string) {
+	if path := os.Getenv(cdFileEnv); path != "" {
+		if err := os.WriteFile(path, []byte(dir), 0o600); err == nil {
+			return
+		}
+	}
+	status("Run 'cd %s' to go there.", dir)
+	info("To change directory automatically, add eval \"$(%s shell-init bash)\" to your shell's startup file.", AppName)
+}
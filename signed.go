@@ -0,0 +1,46 @@
+package main
+
+import "strings"
+
+// signedBranches returns the branches whose tip commit carries a good
+// signature, as a hint that they hold reviewed work.
+func signedBranches(branches []string) []string {
+	var signed []string
+	for _, branch := range branches {
+		lines, err := gitLines("log", "-1", "--format=%G?", "refs/heads/"+branch, "--")
+		if err != nil || len(lines) == 0 {
+			continue
+		}
+		// G is a good signature, U a good one from a key of unknown
+		// validity.
+		if lines[0] == "G" || lines[0] == "U" {
+			signed = append(signed, branch)
+		}
+	}
+	return signed
+}
+
+// confirmSignedBranches asks for a separate confirmation before signed
+// branches are force-deleted and returns branches without the signed ones
+// if it is not given.
+func confirmSignedBranches(branches []string) []string {
+	signed := signedBranches(branches)
+	if len(signed) == 0 {
+		return branches
+	}
+
+	warn("\nWARNING: %d of these branches end in a verified signed commit: %s", len(signed), strings.Join(signed, ", "))
+	answer, err := ask("Type 'force' to delete them as well, or anything else to keep them:")
+	if err == nil && answer == "force" {
+		return branches
+	}
+
+	var unsigned []string
+	for _, branch := range branches {
+		if !contains(signed, branch) {
+			unsigned = append(unsigned, branch)
+		}
+	}
+	status("Keeping %d signed branches.", len(signed))
+	return unsigned
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+)
+
+// confirmForcedBranches handles --smart-force. Branches merged into a base
+// are deleted normally; the unmerged ones are listed with the commits they
+// would lose and only force-deleted after a separate confirmation. It
+// returns the branches still to delete and which of them need force.
+func confirmForcedBranches(branches []string) ([]string, []string) {
+	merged, err := mergedIntoAnyBase()
+	if err != nil {
+		warn(err.Error())
+		exit(1)
+	}
+
+	var unmerged []string
+	for _, branch := range branches {
+		if !contains(merged, branch) {
+			unmerged = append(unmerged, branch)
+		}
+	}
+	if len(unmerged) == 0 {
+		return branches, nil
+	}
+
+	showLostCommits(unmerged, branches)
+	warn("\nWARNING: %s not merged: %s", branchCount(len(unmerged)), strings.Join(unmerged, ", "))
+	answer, err := ask("Type 'force' to force-delete them, or anything else to keep them:")
+	if err == nil && answer == "force" {
+		return branches, unmerged
+	}
+
+	rest := branches
+	for _, branch := range unmerged {
+		rest = removeName(rest, branch)
+	}
+	status("Keeping %s.", branchCount(len(unmerged)))
+	return rest, nil
+}
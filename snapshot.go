@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+)
+
+type snapshot struct {
+	Created  time.Time        `json:"created"`
+	Branches []snapshotBranch `json:"branches"`
+}
+
+type snapshotBranch struct {
+	Name string `json:"name"`
+	SHA  string `json:"sha"`
+}
+
+func runSnapshot(args []string) {
+	switch {
+	case len(args) == 2 && args[0] == "save":
+		saveSnapshot(args[1])
+	case (len(args) == 2 || len(args) == 3) && args[0] == "restore":
+		pattern := ""
+		if len(args) == 3 {
+			pattern = args[2]
+		}
+		restoreSnapshot(args[1], pattern)
+	default:
+		usageError("snapshot")
+	}
+}
+
+// saveSnapshot writes every local branch and its tip SHA to path as JSON.
+func saveSnapshot(path string) {
+	tips, err := branchTips()
+	if err != nil {
+		warn("Error listing branches: %s", err)
+		exit(1)
+	}
+
+	snap := snapshot{Created: time.Now()}
+	for name, sha := range tips {
+		snap.Branches = append(snap.Branches, snapshotBranch{Name: name, SHA: sha})
+	}
+	sort.Slice(snap.Branches, func(i, j int) bool {
+		return snap.Branches[i].Name < snap.Branches[j].Name
+	})
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		fatal("Error encoding snapshot:", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		warn("Error writing snapshot: %s", err)
+		exit(1)
+	}
+
+	branchStr := "branches"
+	if len(snap.Branches) == 1 {
+		branchStr = "branch"
+	}
+	status("Saved %d %s to %s", len(snap.Branches), branchStr, path)
+}
+
+// restoreSnapshot recreates the branches recorded in path that no longer
+// exist, optionally limited to those matching pattern. Existing branches are
+// never moved.
+func restoreSnapshot(path, pattern string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		warn("Error reading snapshot: %s", err)
+		exit(1)
+	}
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		warn("Error parsing snapshot %s: %s", path, err)
+		exit(1)
+	}
+
+	tips, err := branchTips()
+	if err != nil {
+		warn("Error listing branches: %s", err)
+		exit(1)
+	}
+
+	title("Restoring branches from snapshot taken %s", snap.Created.Format("2006-01-02 15:04"))
+	restored := 0
+	for _, b := range snap.Branches {
+		if pattern != "" && !matchesPattern(b.Name, pattern) {
+			continue
+		}
+
+		if sha, ok := tips[b.Name]; ok {
+			if sha != b.SHA {
+				warn("Skipped %s: branch exists at a different commit", b.Name)
+			}
+			continue
+		}
+
+		if gitQuiet("cat-file", "-e", b.SHA+"^{commit}") != nil {
+			warn("Skipped %s: commit %s is not in this repository", b.Name, b.SHA)
+			continue
+		}
+		if output, err := gitCombinedOutput("branch", b.Name, b.SHA); err != nil {
+			warn("Error restoring %s: %s", b.Name, output)
+			continue
+		}
+		info("Restored %s at %s", b.Name, shortSHA(b.SHA))
+		restored++
+	}
+
+	branchStr := "branches"
+	if restored == 1 {
+		branchStr = "branch"
+	}
+	status("%d %s restored.", restored, branchStr)
+	packRefsIfMany(restored)
+}
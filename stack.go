@@ -0,0 +1,237 @@
+package main
+
+import (
+	"strings"
+)
+
+// runStack lists the stacks of branches built on one another, records the
+// branch one is stacked on, or rebases one stack so that each branch sits on
+// the current tip of the one below.
+func runStack(args []string) {
+	switch {
+	case len(args) == 1 && args[0] == "list":
+		listStacks()
+	case len(args) == 3 && args[0] == "set-parent":
+		setStackParent(args[1], args[2])
+	case len(args) == 2 && args[0] == "restack":
+		top, err := resolveBranchArg(args[1])
+		if err != nil {
+			warn(err.Error())
+			exit(1)
+		}
+		restack(top)
+	default:
+		usageError("stack")
+	}
+}
+
+// stackParentKey is the variable in a branch's git config section that
+// records the branch it is stacked on. Once the bottom of a stack is amended
+// or rebased, what it was forked from can no longer be worked out, so
+// parents are recorded by set-parent and by restack, never by just looking.
+var stackParentKey = AppName + "parent"
+
+// setStackParent records that branch is stacked on parent.
+func setStackParent(branchArg, parentArg string) {
+	branch, err := resolveBranchArg(branchArg)
+	var parent string
+	if err == nil {
+		parent, err = resolveBranchArg(parentArg)
+	}
+	if err != nil {
+		warn(err.Error())
+		exit(1)
+	}
+	if parent == branch {
+		warn("%s cannot be stacked on itself.", branch)
+		exit(1)
+	}
+	if dryRun {
+		status("Dry run, %s was not recorded as stacked on %s.", branch, parent)
+		return
+	}
+	if err := gitRun("config", "branch."+branch+"."+stackParentKey, parent); err != nil {
+		exit(1)
+	}
+	status("Recorded %s as stacked on %s.", branch, parent)
+}
+
+// stackParents maps each branch stacked on another local branch to that
+// branch. A recorded parent comes first, then a local branch the branch
+// tracks; otherwise the branch it was forked from is worked out as in graph.
+// Branches on a base branch are not stacked.
+func stackParents() (map[string]string, error) {
+	branches, _, err := listBranches()
+	if err != nil {
+		return nil, err
+	}
+	sortBranches(branches)
+	edges, err := branchAncestry(branches)
+	if err != nil {
+		return nil, err
+	}
+
+	bases := baseBranches()
+	inferred := make(map[string]string)
+	for _, e := range edges {
+		inferred[e.child] = e.parent
+	}
+
+	parents := make(map[string]string)
+	for _, branch := range branches {
+		parent := recordedStackParent(branch)
+		if parent == "" || !contains(branches, parent) {
+			parent = localUpstream(branch)
+		}
+		if parent == "" || parent == branch {
+			parent = inferred[branch]
+		}
+		if parent != "" && !contains(bases, parent) {
+			parents[branch] = parent
+		}
+	}
+	return parents, nil
+}
+
+// recordedStackParent returns the parent recorded for branch, if any.
+func recordedStackParent(branch string) string {
+	lines, _ := gitLines("config", "--get", "branch."+branch+"."+stackParentKey)
+	if len(lines) == 0 {
+		return ""
+	}
+	return lines[0]
+}
+
+// localUpstream returns the local branch that branch tracks, if it tracks
+// one.
+func localUpstream(branch string) string {
+	remote, _ := gitLines("config", "--get", "branch."+branch+".remote")
+	merge, _ := gitLines("config", "--get", "branch."+branch+".merge")
+	if len(remote) == 0 || remote[0] != "." || len(merge) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(merge[0], "refs/heads/")
+}
+
+func listStacks() {
+	parents, err := stackParents()
+	if err != nil {
+		warn("Error reading branches: %s", err)
+		exit(1)
+	}
+	if len(parents) == 0 {
+		status("No branches are stacked on other branches.")
+		return
+	}
+
+	children := make(map[string][]string)
+	var bottoms []string
+	for child, parent := range parents {
+		children[parent] = append(children[parent], child)
+		if _, stacked := parents[parent]; !stacked && !contains(bottoms, parent) {
+			bottoms = append(bottoms, parent)
+		}
+	}
+	for _, list := range children {
+		sortBranches(list)
+	}
+	sortBranches(bottoms)
+
+	for _, bottom := range bottoms {
+		title("Stack on %s", bottom)
+		showStack(bottom, children, 0)
+	}
+}
+
+// showStack prints branch and the branches stacked on it, indented by depth.
+func showStack(branch string, children map[string][]string, depth int) {
+	line := strings.Repeat("  ", depth) + branch
+	if depth > 0 {
+		line = strings.Repeat("  ", depth-1) + "└ " + branch
+		if plainOutput {
+			line = strings.Repeat("  ", depth-1) + "- " + branch
+		}
+	}
+	info("%s", line)
+	for _, child := range children[branch] {
+		showStack(child, children, depth+1)
+	}
+}
+
+// restack rebases the stack ending in top from the bottom up, each branch
+// onto the current tip of the branch below it.
+func restack(top string) {
+	parents, err := stackParents()
+	if err != nil {
+		warn("Error reading branches: %s", err)
+		exit(1)
+	}
+	var chain []string
+	for branch := top; ; {
+		parent, ok := parents[branch]
+		if !ok || contains(chain, branch) {
+			break
+		}
+		chain = append([]string{branch}, chain...)
+		branch = parent
+	}
+	if len(chain) == 0 {
+		warn("%s is not stacked on another branch.", top)
+		exit(1)
+	}
+
+	if lines, err := gitLines("status", "--porcelain", "--untracked-files=no"); err != nil || len(lines) > 0 {
+		warn("Commit or stash your changes before restacking.")
+		exit(1)
+	}
+
+	// The old tip of each parent, and so where each branch's own commits
+	// start, has to be found before any of them move.
+	forkPoints := make(map[string]string, len(chain))
+	for _, branch := range chain {
+		forkPoints[branch] = forkPoint(parents[branch], branch)
+		if forkPoints[branch] == "" {
+			warn("Cannot find where %s was forked from %s.", branch, parents[branch])
+			exit(1)
+		}
+	}
+
+	original, _ := gitLines("symbolic-ref", "--short", "-q", "HEAD")
+	title("Restacking %s on %s", strings.Join(chain, ", "), parents[chain[0]])
+	if dryRun {
+		for _, branch := range chain {
+			info("Would rebase %s onto %s", branch, parents[branch])
+		}
+		status("Dry run, nothing was rebased.")
+		return
+	}
+	// Record the parents, as after the rebase the bottom of the stack may
+	// no longer be found from its history.
+	for _, branch := range chain {
+		_ = gitQuiet("config", "branch."+branch+"."+stackParentKey, parents[branch])
+	}
+	for _, branch := range chain {
+		info("Rebasing %s onto %s", branch, parents[branch])
+		if err := gitRun("rebase", "--onto", parents[branch], forkPoints[branch], branch); err != nil {
+			warn("Rebasing %s stopped. Resolve the conflicts and run 'git rebase --continue', then run '%s stack restack %s' again.", branch, AppName, top)
+			exit(1)
+		}
+	}
+	if len(original) > 0 {
+		_ = gitQuiet("switch", original[0])
+	}
+	status("Restacked %s.", branchCount(len(chain)))
+}
+
+// forkPoint returns the commit branch was forked from parent at, using the
+// parent's reflog so that a parent that was since amended or rebased is
+// still handled.
+func forkPoint(parent, branch string) string {
+	if lines, err := gitLines("merge-base", "--fork-point", "refs/heads/"+parent, "refs/heads/"+branch); err == nil && len(lines) > 0 {
+		return lines[0]
+	}
+	if lines, err := gitLines("merge-base", "refs/heads/"+parent, "refs/heads/"+branch); err == nil && len(lines) > 0 {
+		return lines[0]
+	}
+	return ""
+}
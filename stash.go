@@ -0,0 +1,44 @@
+package main
+
+import "strings"
+
+// stashesByBranch maps branch names to descriptions of the stashes that were
+// created while that branch was checked out.
+func stashesByBranch() map[string][]string {
+	lines, err := gitLines("stash", "list", "--format=%gd%x00%gs")
+	if err != nil {
+		return nil
+	}
+
+	stashes := make(map[string][]string)
+	for _, line := range lines {
+		ref, subject, _ := strings.Cut(line, "\x00")
+		rest, ok := strings.CutPrefix(subject, "WIP on ")
+		if !ok {
+			rest, ok = strings.CutPrefix(subject, "On ")
+		}
+		if !ok {
+			continue
+		}
+		branch, message, _ := strings.Cut(rest, ": ")
+		stashes[branch] = append(stashes[branch], ref+" "+message)
+	}
+	return stashes
+}
+
+// warnAboutStashes lists stashes made on any of branches, since they lose
+// their context once the branch is gone.
+func warnAboutStashes(branches []string) {
+	stashes := stashesByBranch()
+
+	shown := false
+	for _, branch := range branches {
+		for _, stash := range stashes[branch] {
+			if !shown {
+				title("These stashes were created on branches about to be deleted:")
+				shown = true
+			}
+			warn("%s: %s", branch, stash)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checkoutStats counts how often a branch was checked out according to the
+// HEAD reflog.
+type checkoutStats struct {
+	count int
+	last  time.Time
+}
+
+func runStats(args []string) {
+	checkouts, args := extractFlag(args, "--checkouts")
+	if !checkouts || len(args) != 0 {
+		usageError("stats")
+	}
+	showCheckoutStats()
+}
+
+// readCheckoutStats reads the last reflogScanLimit entries of the HEAD
+// reflog and returns the checkout stats of each branch, and the time of the
+// oldest entry read.
+func readCheckoutStats() (map[string]checkoutStats, time.Time, error) {
+	// %gd with --date=unix gives the time of the reflog entry; %ct would
+	// give the date of the commit checked out.
+	lines, err := gitLines("reflog", "show", "--date=unix", "--format=%gd%x00%gs", "-n", strconv.Itoa(reflogScanLimit), "HEAD", "--")
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	stats, oldest := parseCheckoutStats(lines)
+	return stats, oldest, nil
+}
+
+// parseCheckoutStats counts the checkouts in reflog lines of the form
+// HEAD@{<unix time>}\x00<subject>, newest first.
+func parseCheckoutStats(lines []string) (map[string]checkoutStats, time.Time) {
+	stats := make(map[string]checkoutStats)
+	var oldest time.Time
+	for _, line := range lines {
+		selector, subject, _ := strings.Cut(line, "\x00")
+		when, ok := reflogSelectorTime(selector)
+		if !ok {
+			continue
+		}
+		oldest = when
+
+		move, ok := strings.CutPrefix(subject, "checkout: moving from ")
+		if !ok {
+			continue
+		}
+		_, to, ok := strings.Cut(move, " to ")
+		if !ok {
+			continue
+		}
+		s := stats[to]
+		s.count++
+		if when.After(s.last) {
+			s.last = when
+		}
+		stats[to] = s
+	}
+	return stats, oldest
+}
+
+// reflogSelectorTime reads the time from a reflog selector such as
+// HEAD@{1700000000}, as shown with --date=unix.
+func reflogSelectorTime(selector string) (time.Time, bool) {
+	_, braced, ok := strings.Cut(selector, "@{")
+	if !ok || !strings.HasSuffix(braced, "}") {
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSuffix(braced, "}"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+func showCheckoutStats() {
+	stats, oldest, err := readCheckoutStats()
+	var branches []string
+	if err == nil {
+		branches, _, err = listBranches()
+	}
+	if err != nil {
+		warn("Error reading the reflog: %s", err)
+		exit(1)
+	}
+
+	sort.Slice(branches, func(i, j int) bool {
+		a, b := stats[branches[i]], stats[branches[j]]
+		if a.count != b.count {
+			return a.count > b.count
+		}
+		return branches[i] < branches[j]
+	})
+
+	t := table{alignRight: map[int]bool{1: true}}
+	if oldest.IsZero() {
+		title("Checkouts (the reflog is empty)")
+	} else {
+		title("Checkouts since %s (the reflog's history)", formatWhen(oldest))
+	}
+	for i, branch := range branches {
+		s := stats[branch]
+		last := "never"
+		if s.count > 0 {
+			last = formatWhen(s.last)
+		}
+		if plainOutput {
+			info("branch %d of %d: %s, %d checkouts, last %s", i+1, len(branches), branch, s.count, last)
+		} else {
+			t.addRow(branch, strconv.Itoa(s.count), last)
+		}
+	}
+	for _, line := range t.lines() {
+		info("%s", line)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReflogSelectorTime(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     int64
+		ok       bool
+	}{
+		{"HEAD@{1700000000}", 1700000000, true},
+		{"refs/heads/main@{5}", 5, true},
+		{"HEAD@{2 days ago}", 0, false},
+		{"HEAD@{1700000000", 0, false},
+		{"HEAD", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := reflogSelectorTime(tt.selector)
+		if ok != tt.ok || (ok && got.Unix() != tt.want) {
+			t.Errorf("reflogSelectorTime(%q) = %v, %v; want %d, %v", tt.selector, got.Unix(), ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseCheckoutStats(t *testing.T) {
+	// Newest first, as git reflog shows them.
+	lines := []string{
+		"HEAD@{400}\x00checkout: moving from main to feature/a",
+		"HEAD@{300}\x00commit: work",
+		"HEAD@{200}\x00checkout: moving from feature/a to main",
+		"HEAD@{100}\x00checkout: moving from main to feature/a",
+		"not a reflog line",
+		"HEAD@{50}\x00checkout: moving from feature/b to main",
+	}
+	stats, oldest := parseCheckoutStats(lines)
+
+	if oldest.Unix() != 50 {
+		t.Errorf("oldest = %d, want 50", oldest.Unix())
+	}
+	want := map[string]checkoutStats{
+		"feature/a": {count: 2, last: time.Unix(400, 0)},
+		"main":      {count: 2, last: time.Unix(200, 0)},
+	}
+	if len(stats) != len(want) {
+		t.Errorf("got stats for %d branches, want %d: %v", len(stats), len(want), stats)
+	}
+	for branch, w := range want {
+		got := stats[branch]
+		if got.count != w.count || !got.last.Equal(w.last) {
+			t.Errorf("stats[%q] = %d, %d; want %d, %d", branch, got.count, got.last.Unix(), w.count, w.last.Unix())
+		}
+	}
+}
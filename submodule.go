@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+type submodule struct {
+	name string
+	path string
+}
+
+// listSubmodules returns the initialized submodules of the current
+// repository, recursively, labelled by their path relative to the working
+// directory.
+func listSubmodules() ([]submodule, error) {
+	lines, err := gitLines("submodule", "foreach", "--quiet", "--recursive", `printf '%s\t%s\n' "$displaypath" "$toplevel/$sm_path"`)
+	if err != nil {
+		return nil, err
+	}
+
+	var submodules []submodule
+	for _, line := range lines {
+		name, path, _ := strings.Cut(line, "\t")
+		submodules = append(submodules, submodule{name: name, path: path})
+	}
+	return submodules, nil
+}
+
+// foreachSubmodule runs this tool with args inside every submodule and
+// summarises which runs succeeded. The global options given to this run,
+// such as --dry-run and --base, are passed on to each of them.
+func foreachSubmodule(args []string) {
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		usageError("foreach-submodule")
+	}
+
+	submodules, err := listSubmodules()
+	if err != nil {
+		warn("Error listing submodules: %s", err)
+		exit(1)
+	}
+	if len(submodules) == 0 {
+		status("No initialized submodules.")
+		return
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		fatal("Cannot locate executable:", err)
+	}
+	if plainOutput {
+		args = append([]string{"--plain"}, args...)
+	}
+	if logFilePath != "" {
+		args = append([]string{"--log-file", logFilePath}, args...)
+	}
+	if remoteName != "origin" {
+		args = append([]string{"--remote", remoteName}, args...)
+	}
+	for i := len(baseOverrides) - 1; i >= 0; i-- {
+		args = append([]string{"--base", baseOverrides[i]}, args...)
+	}
+	if dryRun {
+		args = append([]string{"--dry-run"}, args...)
+	}
+
+	failed := make(map[string]string)
+	for _, sm := range submodules {
+		title("Submodule %s", sm.name)
+		cmd := exec.Command(self, args...)
+		cmd.Dir = sm.path
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			failed[sm.name] = err.Error()
+		}
+	}
+
+	title("Results")
+	for _, sm := range submodules {
+		if errMsg, ok := failed[sm.name]; ok {
+			warn("%s: failed (%s)", sm.name, errMsg)
+		} else {
+			info("%s: ok", sm.name)
+		}
+	}
+	status("%d out of %d submodules succeeded.", len(submodules)-len(failed), len(submodules))
+	if len(failed) > 0 {
+		exit(1)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// suggestion is a cleanup command worth running and why.
+type suggestion struct {
+	reason  string
+	command string
+}
+
+func runSuggest(args []string) {
+	if len(args) != 0 {
+		usageError("suggest")
+	}
+	suggestions, err := cleanupSuggestions()
+	if err != nil {
+		warn("Error analyzing branches: %s", err)
+		exit(1)
+	}
+	if len(suggestions) == 0 {
+		status("Nothing to clean up.")
+		return
+	}
+	showSuggestions(suggestions)
+}
+
+// cleanupSuggestions looks for branches that could be cleaned up and
+// suggests the commands that would do it.
+func cleanupSuggestions() ([]suggestion, error) {
+	branches, current, err := listBranches()
+	if err != nil {
+		return nil, err
+	}
+	candidates, _ := partitionDeletable(branches, current, false)
+
+	merged, err := mergedIntoAnyBase()
+	if err != nil {
+		return nil, err
+	}
+	gone, err := goneBranches()
+	if err != nil {
+		return nil, err
+	}
+	dates, err := branchCommitDates()
+	if err != nil {
+		return nil, err
+	}
+
+	var mergedCount, goneCount, staleCount int
+	for _, branch := range candidates {
+		switch {
+		case contains(merged, branch):
+			mergedCount++
+		case contains(gone, branch):
+			goneCount++
+		case time.Since(dates[branch]) >= staleAfter:
+			staleCount++
+		}
+	}
+
+	var suggestions []suggestion
+	if mergedCount > 0 {
+		suggestions = append(suggestions, suggestion{
+			reason:  branchCount(mergedCount) + " merged into " + strings.Join(baseBranches(), " or "),
+			command: "delete --merged",
+		})
+	}
+	if goneCount > 0 {
+		suggestions = append(suggestions, suggestion{
+			reason:  branchCount(goneCount) + " unmerged, with their upstream deleted",
+			command: "delete --gone --smart-force",
+		})
+	}
+	if staleCount > 0 {
+		suggestions = append(suggestions, suggestion{
+			reason:  branchCount(staleCount) + " unmerged, without commits for " + formatDuration(staleAfter),
+			command: "edit --stale",
+		})
+	}
+	if violations, err := evaluatePolicies(); err == nil && len(violations) > 0 {
+		suggestions = append(suggestions, suggestion{
+			reason:  plural(len(violations), "policy violation"),
+			command: "check",
+		})
+	}
+	return suggestions, nil
+}
+
+func showSuggestions(suggestions []suggestion) {
+	title("Suggestions")
+	for _, s := range suggestions {
+		if plainOutput {
+			info("%s: run '%s %s'", s.reason, AppName, s.command)
+		} else {
+			info("%s → %s %s", s.reason, AppName, s.command)
+		}
+	}
+}
+
+// showListSuggestions adds the suggestions, if any, below the list when
+// list_suggestions is on.
+func showListSuggestions() {
+	if !config().ListSuggestions {
+		return
+	}
+	if suggestions, err := cleanupSuggestions(); err == nil && len(suggestions) > 0 {
+		showSuggestions(suggestions)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func runSwitch(args []string) {
+	as, _, args := extractOption(args, "--as")
+	autostash, args := extractFlag(args, "--autostash")
+	worktree, args := extractFlag(args, "--worktree")
+	if len(args) != 1 {
+		usageError("switch")
+	}
+	to := switcher{autostash: autostash, worktree: worktree}
+
+	if args[0] == "-" {
+		branch, err := previousBranch()
+		if err != nil {
+			warn(err.Error())
+			exit(1)
+		}
+		to.branch(branch)
+		return
+	}
+
+	if strings.HasPrefix(args[0], "r") {
+		if ref, err := resolveRemoteIndex(args[0]); err == nil {
+			switchToRemoteBranch(ref, as, to)
+			return
+		} else if _, numeric := strconv.Atoi(args[0][1:]); numeric == nil {
+			warn(err.Error())
+			exit(1)
+		}
+	}
+
+	branch, err := resolveBranchArg(args[0])
+	if err != nil {
+		warn(err.Error())
+		exit(1)
+	}
+	to.branch(branch)
+}
+
+// switcher checks branches out, either in the current worktree or, with
+// worktree set, by changing to a worktree of their own.
+type switcher struct {
+	autostash bool
+	worktree  bool
+}
+
+func (s switcher) branch(branch string) {
+	if s.worktree {
+		enterWorktree(branch, "")
+		return
+	}
+	switchBranch(s.autostash, branch)
+}
+
+// tracking creates localName tracking the remote branch ref and checks it
+// out.
+func (s switcher) tracking(localName, ref string) {
+	if s.worktree {
+		enterWorktree(localName, ref)
+		return
+	}
+	switchBranch(s.autostash, "-c", localName, "--track", ref)
+}
+
+// previousBranch returns the branch checked out before the current one,
+// according to the reflog.
+func previousBranch() (string, error) {
+	lines, err := gitLines("rev-parse", "--symbolic-full-name", "@{-1}")
+	if err != nil || len(lines) == 0 {
+		return "", fmt.Errorf("no previous branch to switch to")
+	}
+	branch, ok := strings.CutPrefix(lines[0], "refs/heads/")
+	if !ok || !branchExists(branch) {
+		return "", fmt.Errorf("the previous checkout was not a branch that still exists")
+	}
+	return branch, nil
+}
+
+// sortedRemoteBranches returns the remote-tracking branches in the order
+// `list --remotes` shows them, which defines the rN indexes.
+func sortedRemoteBranches() ([]string, error) {
+	refs, err := listRemoteTrackingBranches()
+	if err != nil {
+		return nil, err
+	}
+	sortBranches(refs)
+	return refs, nil
+}
+
+// resolveRemoteIndex maps an index such as r3 to a remote-tracking branch.
+func resolveRemoteIndex(arg string) (string, error) {
+	index, err := strconv.Atoi(strings.TrimPrefix(arg, "r"))
+	if err != nil {
+		return "", fmt.Errorf("%s is not a remote branch index", arg)
+	}
+
+	refs, err := sortedRemoteBranches()
+	if err != nil {
+		return "", err
+	}
+	if index < 1 || index > len(refs) {
+		return "", fmt.Errorf("index %s is out of range; run '%s list --remotes' to see remote branch indexes", arg, AppName)
+	}
+	return refs[index-1], nil
+}
+
+// splitRemoteBranch splits a remote-tracking branch such as origin/feature/x
+// into its remote and branch name.
+func splitRemoteBranch(ref string) (string, string) {
+	remotes, _ := gitLines("remote")
+	best := ""
+	for _, remote := range remotes {
+		if strings.HasPrefix(ref, remote+"/") && len(remote) > len(best) {
+			best = remote
+		}
+	}
+	if best == "" {
+		remote, branch, _ := strings.Cut(ref, "/")
+		return remote, branch
+	}
+	return best, strings.TrimPrefix(ref, best+"/")
+}
+
+// switchToRemoteBranch checks out a local branch tracking ref, creating it
+// unless a local branch already tracks ref.
+func switchToRemoteBranch(ref, localName string, to switcher) {
+	_, branch := splitRemoteBranch(ref)
+	if localName == "" {
+		localName = branch
+	}
+
+	if branchExists(localName) {
+		upstream, _ := gitLines("rev-parse", "--abbrev-ref", localName+"@{upstream}")
+		if len(upstream) > 0 && upstream[0] == ref {
+			info("%s already tracks %s", localName, ref)
+			to.branch(localName)
+			return
+		}
+		warn("A local branch named %s already exists and does not track %s.", localName, ref)
+		warn("Use --as <name> to check %s out under another name.", ref)
+		exit(1)
+	}
+
+	to.tracking(localName, ref)
+}
+
+// listRemoteBranches prints the remote-tracking branches matching sel's
+// pattern with their rN indexes.
+func listRemoteBranches(sel selection) {
+	refs, err := sortedRemoteBranches()
+	if err != nil {
+		warn("Error listing remote branches: %s", err)
+		exit(1)
+	}
+
+	title("Remote branches")
+	for i, ref := range refs {
+		if sel.pattern != "" && matchesPatternCase(ref, sel.pattern, sel.ignoreCase) == sel.invert {
+			continue
+		}
+		if plainOutput {
+			info("remote branch r%d of %d: %s", i+1, len(refs), ref)
+		} else {
+			info("r%-3d %s", i+1, ref)
+		}
+	}
+}
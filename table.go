@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// table lays out rows of cells in columns. Cells are padded by their display
+// width rather than their length in bytes, so East Asian characters and emoji
+// in branch names or author names keep the columns aligned.
+type table struct {
+	rows [][]string
+	// alignRight marks the columns that are aligned to the right.
+	alignRight map[int]bool
+}
+
+func (t *table) addRow(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// lines returns the rows with their columns padded and separated by two
+// spaces, without trailing spaces.
+func (t *table) lines() []string {
+	var widths []int
+	for _, row := range t.rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+
+	lines := make([]string, len(t.rows))
+	for r, row := range t.rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			if t.alignRight[i] {
+				cells[i] = padLeft(cell, widths[i])
+			} else {
+				cells[i] = padRight(cell, widths[i])
+			}
+		}
+		lines[r] = strings.TrimRight(strings.Join(cells, "  "), " ")
+	}
+	return lines
+}
+
+// displayWidth returns the number of terminal columns s takes up.
+func displayWidth(s string) int {
+	return runewidth.StringWidth(s)
+}
+
+// padRight pads s with spaces to width terminal columns.
+func padRight(s string, width int) string {
+	return s + strings.Repeat(" ", max(0, width-displayWidth(s)))
+}
+
+// padLeft is padRight, putting the spaces before s.
+func padLeft(s string, width int) string {
+	return strings.Repeat(" ", max(0, width-displayWidth(s))) + s
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// theme sets the colors of the output printers.
+type theme struct {
+	title  []color.Attribute
+	status []color.Attribute
+	warn   []color.Attribute
+	// info alternates between two shades so long lists are easier to
+	// follow.
+	info [2][]color.Attribute
+	// statusPrefix and warnPrefix mark their lines so that outcomes and
+	// warnings can be told apart without relying on color.
+	statusPrefix string
+	warnPrefix   string
+}
+
+var themes = map[string]theme{
+	"default": {
+		title:  []color.Attribute{color.FgGreen, color.Bold},
+		status: []color.Attribute{color.FgBlue, color.Bold},
+		warn:   []color.Attribute{color.FgYellow, color.Bold},
+		info:   [2][]color.Attribute{{color.FgCyan}, {color.FgHiCyan}},
+	},
+	// colorblind avoids telling states apart by green, yellow and cyan,
+	// which look alike with deuteranopia and protanopia, using blue and
+	// magenta, brightness and symbols instead.
+	"colorblind": {
+		title:        []color.Attribute{color.FgBlue, color.Bold, color.Underline},
+		status:       []color.Attribute{color.FgBlue, color.Bold},
+		warn:         []color.Attribute{color.FgMagenta, color.Bold},
+		info:         [2][]color.Attribute{{color.Reset}, {color.FgHiBlue}},
+		statusPrefix: "✓ ",
+		warnPrefix:   "! ",
+	},
+}
+
+// themeNames lists the built-in themes for messages.
+func themeNames() string {
+	var names []string
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// setTheme replaces the printers with ones using the theme called name.
+func setTheme(name string) error {
+	t, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q; use one of %s", name, themeNames())
+	}
+
+	titlef := color.New(t.title...).PrintfFunc()
+	title = func(format string, a ...interface{}) {
+		titlef("\n"+format+"\n", a...)
+	}
+
+	statusf := color.New(t.status...).PrintfFunc()
+	status = func(format string, a ...interface{}) {
+		statusf("\n"+withPrefix(t.statusPrefix, format)+"\n\n", a...)
+	}
+
+	shades := [2]func(string, ...interface{}){
+		color.New(t.info[0]...).PrintfFunc(),
+		color.New(t.info[1]...).PrintfFunc(),
+	}
+	shade := 1
+	info = func(format string, a ...interface{}) {
+		shade = 1 - shade
+		shades[shade](format+"\n", a...)
+	}
+
+	warnf := color.New(t.warn...).PrintfFunc()
+	warn = func(format string, a ...interface{}) {
+		warnf(withPrefix(t.warnPrefix, format)+"\n", a...)
+	}
+	return nil
+}
+
+// withPrefix puts prefix before the text of format, after any blank lines
+// it starts with.
+func withPrefix(prefix, format string) string {
+	text := strings.TrimLeft(format, "\n")
+	return format[:len(format)-len(text)] + prefix + text
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trashRefPrefix is where the tips of deleted branches are kept, one
+// refs/<app>/trash/<run>/<branch> ref per branch, so that undo and restore
+// can bring them back and their commits are not garbage collected until
+// expire removes them.
+var trashRefPrefix = "refs/" + AppName + "/trash/"
+
+// trashRunFormat names a deletion run by when it happened, so that runs
+// sort in time order.
+const trashRunFormat = "20060102-150405.000"
+
+// trashedBranch is a deleted branch kept in the trash.
+type trashedBranch struct {
+	run  string
+	name string
+	sha  string
+}
+
+func (t trashedBranch) ref() string {
+	return trashRefPrefix + t.run + "/" + t.name
+}
+
+func (t trashedBranch) deleted() time.Time {
+	when, _ := time.ParseInLocation(trashRunFormat, t.run, time.UTC)
+	return when
+}
+
+// currentTrashRun is the run every deletion of this invocation is recorded
+// under, so that undo restores all of them together, such as both halves of
+// a --smart-force deletion.
+var currentTrashRun string
+
+// trashRun returns the run of this invocation, starting it on first use.
+func trashRun() string {
+	if currentTrashRun == "" {
+		currentTrashRun = time.Now().UTC().Format(trashRunFormat)
+	}
+	return currentTrashRun
+}
+
+// trashBranches records the tips of branches before they are deleted, in
+// the run of this invocation. It returns the run so that the entries of
+// branches that could not be deleted can be dropped again.
+func trashBranches(branches []string) (string, error) {
+	tips, err := branchTips()
+	if err != nil {
+		return "", err
+	}
+	run := trashRun()
+	var commands strings.Builder
+	for _, branch := range branches {
+		if sha, ok := tips[branch]; ok {
+			fmt.Fprintf(&commands, "update %s%s/%s %s\n", trashRefPrefix, run, branch, sha)
+		}
+	}
+	if output, err := gitWithInput(commands.String(), "update-ref", "--stdin"); err != nil {
+		return "", fmt.Errorf("%s", strings.TrimSpace(string(output)))
+	}
+	return run, nil
+}
+
+// untrashBranches drops the entries of run for branches that are still
+// there.
+func untrashBranches(run string, branches []string) {
+	var commands strings.Builder
+	for _, branch := range branches {
+		fmt.Fprintf(&commands, "delete %s%s/%s\n", trashRefPrefix, run, branch)
+	}
+	_, _ = gitWithInput(commands.String(), "update-ref", "--stdin")
+}
+
+// trashedBranches lists the trash, oldest run first.
+func trashedBranches() ([]trashedBranch, error) {
+	lines, err := gitLines("for-each-ref", "--format=%(refname) %(objectname)", trashRefPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var trashed []trashedBranch
+	for _, line := range lines {
+		ref, sha, _ := strings.Cut(line, " ")
+		run, name, ok := strings.Cut(strings.TrimPrefix(ref, trashRefPrefix), "/")
+		if ok {
+			trashed = append(trashed, trashedBranch{run: run, name: name, sha: sha})
+		}
+	}
+	sort.SliceStable(trashed, func(i, j int) bool { return trashed[i].run < trashed[j].run })
+	return trashed, nil
+}
+
+// runUndo recreates the branches deleted by the last run that deleted any.
+func runUndo(args []string) {
+	if len(args) != 0 {
+		usageError("undo")
+	}
+	trashed, err := trashedBranches()
+	if err != nil {
+		warn("Error reading the trash: %s", err)
+		exit(1)
+	}
+	if len(trashed) == 0 {
+		status("There is no deletion to undo.")
+		return
+	}
+	last := trashed[len(trashed)-1].run
+	var run []trashedBranch
+	for _, t := range trashed {
+		if t.run == last {
+			run = append(run, t)
+		}
+	}
+	title("Restoring %s deleted %s", branchCount(len(run)), formatWhen(run[0].deleted()))
+	if restoreTrashed(run) < len(run) {
+		exit(1)
+	}
+}
+
+// runRestore recreates one deleted branch from its last deletion, or lists
+// the trash when no branch is named.
+func runRestore(args []string) {
+	if len(args) > 1 {
+		usageError("restore")
+	}
+	trashed, err := trashedBranches()
+	if err != nil {
+		warn("Error reading the trash: %s", err)
+		exit(1)
+	}
+	if len(args) == 0 {
+		showTrash(trashed)
+		return
+	}
+
+	for i := len(trashed) - 1; i >= 0; i-- {
+		if trashed[i].name == args[0] {
+			if restoreTrashed(trashed[i:i+1]) == 0 {
+				exit(1)
+			}
+			return
+		}
+	}
+	warn("%s is not in the trash. Run '%s restore' to see the deleted branches.", args[0], AppName)
+	exit(1)
+}
+
+// restoreTrashed recreates the trashed branches and removes them from the
+// trash, returning how many were restored. A branch that exists again is
+// left alone.
+func restoreTrashed(trashed []trashedBranch) int {
+	if dryRun {
+		for _, t := range trashed {
+			info("Would restore %s at %s", t.name, shortSHA(t.sha))
+		}
+		status("Dry run, nothing was restored.")
+		return len(trashed)
+	}
+	restored := 0
+	for _, t := range trashed {
+		if branchExists(t.name) {
+			warn("Skipped %s: a branch of that name exists again", t.name)
+			continue
+		}
+		if output, err := gitCombinedOutput("branch", t.name, t.sha); err != nil {
+			warn("Error restoring %s: %s", t.name, output)
+			continue
+		}
+		_ = gitQuiet("update-ref", "-d", t.ref())
+		info("Restored %s at %s", t.name, shortSHA(t.sha))
+		restored++
+	}
+	status("%d out of %s restored.", restored, branchCount(len(trashed)))
+	return restored
+}
+
+func showTrash(trashed []trashedBranch) {
+	if len(trashed) == 0 {
+		status("The trash is empty.")
+		return
+	}
+	title("Deleted branches, newest first")
+	t := table{}
+	for i := len(trashed) - 1; i >= 0; i-- {
+		b := trashed[i]
+		if plainOutput {
+			info("%s, deleted %s, at %s", b.name, formatWhen(b.deleted()), shortSHA(b.sha))
+		} else {
+			t.addRow(b.name, "deleted "+formatWhen(b.deleted()), shortSHA(b.sha))
+		}
+	}
+	for _, line := range t.lines() {
+		info("%s", line)
+	}
+}
+
+// expiredTrash returns the trashed branches deleted before cutoff.
+func expiredTrash(cutoff time.Time) ([]trashedBranch, error) {
+	trashed, err := trashedBranches()
+	if err != nil {
+		return nil, err
+	}
+	var expired []trashedBranch
+	for _, t := range trashed {
+		if t.deleted().Before(cutoff) {
+			expired = append(expired, t)
+		}
+	}
+	return expired, nil
+}
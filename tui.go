@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// tuiState is the branch picker of `tui`: every candidate, which of them
+// are ticked, and the filter narrowing down the ones shown.
+type tuiState struct {
+	branches  []branchInfo
+	selected  map[string]bool
+	filter    string
+	filtering bool
+	cursor    int
+	offset    int
+	rows      int
+	// message reports the outcome of the last key, such as a copy, until
+	// the next one.
+	message string
+}
+
+// runTUI lets the user tick the branches to delete in a full-screen list,
+// then deletes them with the usual preview and confirmation.
+func runTUI(args []string) {
+	opts, rest := parseDeleteOptions(args, false)
+	sel, rest, err := parseSelection(rest)
+	if err != nil {
+		fatal(err)
+	}
+	if len(rest) > 1 {
+		usageError("tui")
+	}
+	if len(rest) == 1 {
+		sel.pattern = rest[0]
+	}
+	if runtime.GOOS == "windows" || !openTTY() {
+		warn("tui needs a terminal. Use '%s edit' or '%s delete' instead.", AppName, AppName)
+		exit(1)
+	}
+
+	branches, current, err := listBranches()
+	if err == nil {
+		branches, err = sel.filter(branches)
+	}
+	if err != nil {
+		warn("Error selecting branches: %s", err)
+		exit(1)
+	}
+	sortBranches(branches)
+	candidates, skipped := partitionDeletable(branches, current, false)
+	if len(candidates) == 0 {
+		showSkippedBranches(skipped)
+		status("No branches to delete.")
+		return
+	}
+	infos, err := loadBranchInfos(candidates)
+	if err != nil {
+		warn("Error reading branch details: %s", err)
+		exit(1)
+	}
+
+	state := &tuiState{selected: make(map[string]bool)}
+	for _, branch := range candidates {
+		state.branches = append(state.branches, infos[branch])
+	}
+	chosen, ok := state.run()
+	if !ok || len(chosen) == 0 {
+		status("No branches selected for deletion.")
+		return
+	}
+	confirmAndDeleteBranches(chosen, current, opts)
+}
+
+// run shows the picker until the user confirms or quits, returning the
+// ticked branches in list order.
+func (s *tuiState) run() ([]string, bool) {
+	saved, err := stty("-g")
+	if err != nil {
+		warn("Cannot set up the terminal: %s", err)
+		exit(1)
+	}
+	_, _ = stty("raw", "-echo")
+	// The alternate screen keeps the picker out of the scrollback.
+	fmt.Fprint(tty, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Fprint(tty, "\x1b[?25h\x1b[?1049l")
+		_, _ = stty(strings.TrimSpace(saved))
+	}()
+
+	s.rows = 24
+	if size, err := stty("size"); err == nil {
+		if fields := strings.Fields(size); len(fields) == 2 {
+			if rows, err := strconv.Atoi(fields[0]); err == nil && rows > 5 {
+				s.rows = rows
+			}
+		}
+	}
+
+	keys := ttyReader
+	for {
+		s.draw()
+		key, err := readKey(keys)
+		if err != nil {
+			return nil, false
+		}
+		s.message = ""
+		if s.filtering {
+			s.editFilter(key)
+			continue
+		}
+		switch key {
+		case "up", "k":
+			s.move(-1)
+		case "down", "j":
+			s.move(1)
+		case " ", "x":
+			if shown := s.shown(); len(shown) > 0 {
+				name := shown[s.cursor].Name
+				s.selected[name] = !s.selected[name]
+				s.move(1)
+			}
+		case "a":
+			s.toggleAll()
+		case "c":
+			s.copyName()
+		case "/":
+			s.filtering = true
+		case "enter":
+			var chosen []string
+			for _, b := range s.branches {
+				if s.selected[b.Name] {
+					chosen = append(chosen, b.Name)
+				}
+			}
+			return chosen, true
+		case "q", "ctrl-c", "esc":
+			return nil, false
+		}
+	}
+}
+
+// shown returns the branches matching the filter, as a substring ignoring
+// case.
+func (s *tuiState) shown() []branchInfo {
+	if s.filter == "" {
+		return s.branches
+	}
+	var shown []branchInfo
+	for _, b := range s.branches {
+		if strings.Contains(strings.ToLower(b.Name), strings.ToLower(s.filter)) {
+			shown = append(shown, b)
+		}
+	}
+	return shown
+}
+
+func (s *tuiState) move(delta int) {
+	s.cursor += delta
+	if n := len(s.shown()); s.cursor >= n {
+		s.cursor = n - 1
+	}
+	if s.cursor < 0 {
+		s.cursor = 0
+	}
+}
+
+// copyName puts the name of the branch under the cursor on the clipboard,
+// as copy-name does.
+func (s *tuiState) copyName() {
+	shown := s.shown()
+	if len(shown) == 0 {
+		return
+	}
+	name := shown[s.cursor].Name
+	if err := copyToClipboard(name); err != nil {
+		s.message = "Cannot copy to the clipboard: " + err.Error()
+		return
+	}
+	s.message = "Copied " + name + " to the clipboard"
+}
+
+// toggleAll ticks every shown branch, or unticks them all if they already
+// are.
+func (s *tuiState) toggleAll() {
+	shown := s.shown()
+	all := true
+	for _, b := range shown {
+		all = all && s.selected[b.Name]
+	}
+	for _, b := range shown {
+		s.selected[b.Name] = !all
+	}
+}
+
+func (s *tuiState) editFilter(key string) {
+	switch {
+	case key == "enter" || key == "esc":
+		s.filtering = false
+	case key == "backspace":
+		if s.filter != "" {
+			s.filter = s.filter[:len(s.filter)-1]
+		}
+	case key == "ctrl-c":
+		s.filter, s.filtering = "", false
+	case len(key) == 1:
+		s.filter += key
+	}
+	s.cursor, s.offset = 0, 0
+}
+
+// draw redraws the whole screen. In raw mode lines must end in \r\n.
+func (s *tuiState) draw() {
+	shown := s.shown()
+	count := 0
+	for _, b := range s.branches {
+		if s.selected[b.Name] {
+			count++
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	fmt.Fprintf(&b, "Select branches to delete: %d of %d selected\r\n", count, len(s.branches))
+	b.WriteString("space select  a all  c copy name  / filter  enter delete  q quit\r\n")
+	switch {
+	case s.message != "":
+		b.WriteString(s.message + "\r\n")
+	case s.filtering:
+		fmt.Fprintf(&b, "Filter: %s_\r\n", s.filter)
+	case s.filter != "":
+		fmt.Fprintf(&b, "Filter: %s (%d shown)\r\n", s.filter, len(shown))
+	default:
+		b.WriteString("\r\n")
+	}
+
+	height := s.rows - 4
+	if s.cursor < s.offset {
+		s.offset = s.cursor
+	}
+	if s.cursor >= s.offset+height {
+		s.offset = s.cursor - height + 1
+	}
+	for i := s.offset; i < len(shown) && i < s.offset+height; i++ {
+		br := shown[i]
+		pointer, box := "  ", "[ ]"
+		if i == s.cursor {
+			pointer = "> "
+		}
+		if s.selected[br.Name] {
+			box = "[x]"
+		}
+		line := fmt.Sprintf("%s%s %s  %s  %s", pointer, box, br.Name, formatWhen(br.Date), br.mergedLabel())
+		if i == s.cursor {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		b.WriteString(line + "\r\n")
+	}
+	fmt.Fprint(tty, b.String())
+}
+
+// readKey reads one key press from the terminal in raw mode, naming the
+// special keys the picker uses.
+func readKey(r *bufio.Reader) (string, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	switch c {
+	case '\r', '\n':
+		return "enter", nil
+	case 3:
+		return "ctrl-c", nil
+	case 127, 8:
+		return "backspace", nil
+	case 27:
+		// Arrow keys arrive as ESC [ A and so on. A lone ESC is only told
+		// apart when nothing follows it in the same read.
+		if r.Buffered() == 0 {
+			return "esc", nil
+		}
+		if next, _ := r.ReadByte(); next != '[' && next != 'O' {
+			return "esc", nil
+		}
+		switch final, _ := r.ReadByte(); final {
+		case 'A':
+			return "up", nil
+		case 'B':
+			return "down", nil
+		}
+		return "", nil
+	}
+	return string(c), nil
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// whereEnv is the branch metadata a --where expression is evaluated against.
+type whereEnv struct {
+	info branchInfo
+	gone bool
+	mine bool
+}
+
+// whereExpr is a parsed --where expression such as
+// `age > 30d && merged && author != "me"`.
+type whereExpr interface {
+	eval(env whereEnv) (interface{}, error)
+}
+
+type whereToken struct {
+	kind  string // "ident", "string", "age", "op" or "eof"
+	value string
+}
+
+// parseWhere parses a --where expression. Supported fields are name, author,
+// email, age, merged, gone and mine; operators are && || ! == != < <= > >=
+// and ~ (wildcard pattern match), with parentheses for grouping.
+func parseWhere(src string) (whereExpr, error) {
+	tokens, err := tokenizeWhere(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &whereParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != "eof" {
+		return nil, fmt.Errorf("unexpected %q in --where expression", tok.value)
+	}
+	if err := checkWhereCondition(expr); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// checkWhere reports type errors anywhere in expr, such as comparing age
+// with text, by evaluating each part of it against an empty branch. The
+// types do not depend on the branch, and evaluating the whole expression
+// once would skip the parts that && and || short-circuit.
+func checkWhere(expr whereExpr) error {
+	switch e := expr.(type) {
+	case whereNot:
+		return checkWhereCondition(e.operand)
+	case whereLogic:
+		if err := checkWhereCondition(e.left); err != nil {
+			return err
+		}
+		return checkWhereCondition(e.right)
+	case whereCompare:
+		if err := checkWhere(e.left); err != nil {
+			return err
+		}
+		if err := checkWhere(e.right); err != nil {
+			return err
+		}
+	}
+	_, err := expr.eval(whereEnv{})
+	return err
+}
+
+// checkWhereCondition is checkWhere for an expression that must be a
+// condition.
+func checkWhereCondition(expr whereExpr) error {
+	if err := checkWhere(expr); err != nil {
+		return err
+	}
+	_, err := evalBool(expr, whereEnv{})
+	return err
+}
+
+func tokenizeWhere(src string) ([]whereToken, error) {
+	var tokens []whereToken
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != c {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated string in --where expression")
+			}
+			tokens = append(tokens, whereToken{"string", string(runes[i+1 : end])})
+			i = end + 1
+		case unicode.IsDigit(c):
+			end := i
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || unicode.IsLetter(runes[end])) {
+				end++
+			}
+			tokens = append(tokens, whereToken{"age", string(runes[i:end])})
+			i = end
+		case unicode.IsLetter(c) || c == '_':
+			end := i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+				end++
+			}
+			tokens = append(tokens, whereToken{"ident", string(runes[i:end])})
+			i = end
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "~", "(", ")"} {
+				if strings.HasPrefix(string(runes[i:]), candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q in --where expression", c)
+			}
+			tokens = append(tokens, whereToken{"op", op})
+			i += len(op)
+		}
+	}
+	return append(tokens, whereToken{kind: "eof"}), nil
+}
+
+type whereParser struct {
+	tokens []whereToken
+	pos    int
+}
+
+func (p *whereParser) peek() whereToken {
+	return p.tokens[p.pos]
+}
+
+func (p *whereParser) next() whereToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != "eof" {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *whereParser) acceptOp(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind == "op" && contains(ops, tok.value) {
+		p.pos++
+		return tok.value, true
+	}
+	return "", false
+}
+
+func (p *whereParser) parseOr() (whereExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = whereLogic{op: "||", left: left, right: right}
+	}
+}
+
+func (p *whereParser) parseAnd() (whereExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = whereLogic{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *whereParser) parseUnary() (whereExpr, error) {
+	if _, ok := p.acceptOp("!"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return whereNot{operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *whereParser) parseComparison() (whereExpr, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.acceptOp("==", "!=", "<", "<=", ">", ">=", "~")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return whereCompare{op: op, left: left, right: right}, nil
+}
+
+func (p *whereParser) parsePrimary() (whereExpr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case "op":
+		if tok.value != "(" {
+			return nil, fmt.Errorf("unexpected %q in --where expression", tok.value)
+		}
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.acceptOp(")"); !ok {
+			return nil, fmt.Errorf("missing ')' in --where expression")
+		}
+		return expr, nil
+	case "string":
+		return whereLiteral{tok.value}, nil
+	case "age":
+		d, err := parseAge(tok.value)
+		if err != nil {
+			return nil, err
+		}
+		return whereLiteral{d}, nil
+	case "ident":
+		switch tok.value {
+		case "true":
+			return whereLiteral{true}, nil
+		case "false":
+			return whereLiteral{false}, nil
+		case "name", "author", "email", "age", "merged", "gone", "mine":
+			return whereField(tok.value), nil
+		}
+		return nil, fmt.Errorf("unknown field %q in --where expression", tok.value)
+	}
+	return nil, fmt.Errorf("unexpected end of --where expression")
+}
+
+type whereLiteral struct{ value interface{} }
+
+func (l whereLiteral) eval(whereEnv) (interface{}, error) { return l.value, nil }
+
+type whereField string
+
+func (f whereField) eval(env whereEnv) (interface{}, error) {
+	switch f {
+	case "name":
+		return env.info.Name, nil
+	case "author":
+		return env.info.Author, nil
+	case "email":
+		return env.info.Email, nil
+	case "age":
+		return time.Since(env.info.Date), nil
+	case "merged":
+		return env.info.Merged, nil
+	case "gone":
+		return env.gone, nil
+	default:
+		return env.mine, nil
+	}
+}
+
+type whereNot struct{ operand whereExpr }
+
+func (n whereNot) eval(env whereEnv) (interface{}, error) {
+	v, err := evalBool(n.operand, env)
+	return !v, err
+}
+
+type whereLogic struct {
+	op          string
+	left, right whereExpr
+}
+
+func (l whereLogic) eval(env whereEnv) (interface{}, error) {
+	left, err := evalBool(l.left, env)
+	if err != nil {
+		return nil, err
+	}
+	if (l.op == "&&" && !left) || (l.op == "||" && left) {
+		return left, nil
+	}
+	return evalBool(l.right, env)
+}
+
+type whereCompare struct {
+	op          string
+	left, right whereExpr
+}
+
+func (c whereCompare) eval(env whereEnv) (interface{}, error) {
+	left, err := c.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := c.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch l := left.(type) {
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			break
+		}
+		switch c.op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		case "~":
+			return matchesPattern(l, r), nil
+		}
+	case bool:
+		r, ok := right.(bool)
+		if !ok {
+			break
+		}
+		switch c.op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		}
+	case time.Duration:
+		r, ok := right.(time.Duration)
+		if !ok {
+			break
+		}
+		switch c.op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		case "<":
+			return l < r, nil
+		case "<=":
+			return l <= r, nil
+		case ">":
+			return l > r, nil
+		case ">=":
+			return l >= r, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot apply %s to %s and %s in --where expression", c.op, whereTypeName(left), whereTypeName(right))
+}
+
+func whereTypeName(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "text"
+	case bool:
+		return "a condition"
+	default:
+		return "an age"
+	}
+}
+
+func evalBool(expr whereExpr, env whereEnv) (bool, error) {
+	v, err := expr.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected a condition but got %s in --where expression", whereTypeName(v))
+	}
+	return b, nil
+}
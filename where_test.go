@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWhere(t *testing.T) {
+	env := whereEnv{
+		info: branchInfo{
+			Name:   "feature/login",
+			Author: "Ann",
+			Email:  "ann@example.com",
+			Date:   time.Now().Add(-40 * 24 * time.Hour),
+			Merged: true,
+		},
+		mine: true,
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"merged", true},
+		{"gone", false},
+		{"!gone && mine", true},
+		{"age > 30d", true},
+		{"age >= 6w", false},
+		{"age < 2w || author == 'Ann'", true},
+		{`author != "Ann"`, false},
+		{`name ~ "feature/*"`, true},
+		{`name ~ "fix/*"`, false},
+		{`(age > 90d || merged) && email == "ann@example.com"`, true},
+		{"merged && (gone || !mine)", false},
+		{"merged == true", true},
+	}
+	for _, tt := range tests {
+		expr, err := parseWhere(tt.expr)
+		if err != nil {
+			t.Errorf("parseWhere(%q): %s", tt.expr, err)
+			continue
+		}
+		got, err := evalBool(expr, env)
+		if err != nil || got != tt.want {
+			t.Errorf("%s = %v, %v; want %v", tt.expr, got, err, tt.want)
+		}
+	}
+}
+
+func TestWhereErrors(t *testing.T) {
+	for _, src := range []string{
+		// Expressions that do not parse.
+		"", "age >", "age > 30d )", "(merged", "size > 1", `name == "x`, "merged # x", "age > 30q",
+		// Expressions that compare the wrong types, even where && or ||
+		// would skip them for some branches.
+		"age", "name > 30d", "merged < true", `age == "old"`, `merged && age == "old"`, `gone || !(name == 30d)`,
+	} {
+		if _, err := parseWhere(src); err == nil {
+			t.Errorf("parseWhere(%q) succeeded", src)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// worktreeList returns the path of the main worktree and maps each branch
+// checked out in a worktree to that worktree's path.
+func worktreeList() (string, map[string]string, error) {
+	lines, err := gitLines("worktree", "list", "--porcelain")
+	if err != nil {
+		return "", nil, err
+	}
+
+	var main, path string
+	branches := make(map[string]string)
+	for _, line := range lines {
+		if p, ok := strings.CutPrefix(line, "worktree "); ok {
+			path = p
+			if main == "" {
+				main = p
+			}
+		} else if ref, ok := strings.CutPrefix(line, "branch refs/heads/"); ok {
+			branches[ref] = path
+		}
+	}
+	if main == "" {
+		return "", nil, fmt.Errorf("no worktrees found")
+	}
+	return main, branches, nil
+}
+
+// newWorktreePath returns where a worktree for branch is added: next to the
+// main worktree, named after it and the branch.
+func newWorktreePath(main, branch string) string {
+	name := filepath.Base(main) + "-" + strings.ReplaceAll(branch, "/", "-")
+	return filepath.Join(filepath.Dir(main), name)
+}
+
+// enterWorktree changes to the worktree that has branch checked out, adding
+// one if there is none. With ref set, branch is created to track ref.
+func enterWorktree(branch, ref string) {
+	main, branches, err := worktreeList()
+	if err != nil {
+		warn("Error listing worktrees: %s", err)
+		exit(1)
+	}
+
+	path, ok := branches[branch]
+	if !ok {
+		path = newWorktreePath(main, branch)
+		title("Adding a worktree for %s at %s", branch, path)
+		args := []string{"worktree", "add", path, branch}
+		if ref != "" {
+			args = []string{"worktree", "add", "--track", "-b", branch, path, ref}
+		}
+		if err := gitRun(args...); err != nil {
+			exit(1)
+		}
+	}
+	changeDirectory(path)
+}